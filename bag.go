@@ -0,0 +1,503 @@
+package main // Define the main package
+
+import (
+	"bufio"         // Provides line reading of manifests and tag files
+	"crypto/md5"    // Provides MD5 for validating bags made by other tools
+	"crypto/sha1"   // Provides SHA-1 for validating bags made by other tools
+	"crypto/sha256" // Provides the SHA-256 manifests
+	"crypto/sha512" // Provides the SHA-512 manifests
+	"encoding/hex"  // Provides hex encoding of hashes
+	"encoding/json" // Provides the manifest of the bagged subset
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output
+	"hash"          // Provides the hash.Hash interface
+	"io"            // Provides basic interfaces to I/O primitives
+	"io/fs"         // Provides directory walking
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"net/url"       // Provides the host of the source site
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"runtime/debug" // Provides the build's version control revision
+	"slices"        // Provides slice sorting helpers
+	"strconv"       // Provides number parsing of Payload-Oxum
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides time-related functions
+)
+
+// bagItVersion is the version of the BagIt specification (RFC 8493) the bags follow.
+const bagItVersion = "1.0"
+
+// bagAlgorithms are the checksum algorithms of the payload and tag manifests written into new bags.
+var bagAlgorithms = []string{"sha256", "sha512"}
+
+// bagHashes creates the hash for each algorithm a bag may use; bags from other tools may also use md5 or sha1.
+var bagHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// runBag implements the bag command: package the archive, or the files matching the filters, into a
+// BagIt bag. "bag validate <bag>" checks an existing bag instead.
+func runBag(args []string) int {
+	if len(args) > 0 && args[0] == "validate" {
+		return runBagValidate(args[1:])
+	}
+	// Parse the command line options.
+	flags := flag.NewFlagSet("bag", flag.ExitOnError)
+	types := flags.String("type", "", "comma-separated asset types to include, e.g. pdf,stl (default all)")
+	product := flags.String("product", "", "only include files of this catalog product (name or slug)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bag [-type pdf,stl] [-product name] <new bag directory>\n       bag validate <bag directory>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	archive := loadManifest(manifestPath)
+	selected := selectBagEntries(archive, *types, *product)
+	if len(selected) == 0 {
+		log.Printf("No archived files match the filters")
+		return 1
+	}
+	if !writeBag(flags.Arg(0), archive, selected) {
+		return 1
+	}
+	return 0
+}
+
+// selectBagEntries returns the manifest entries on disk that match the type and product filters, sorted by path.
+func selectBagEntries(archive *archiveManifest, types, product string) []*manifestEntry {
+	wantedTypes := make(map[string]bool)
+	for _, name := range strings.Split(strings.ToLower(types), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			wantedTypes[name] = true
+		}
+	}
+	var selected []*manifestEntry
+	for _, key := range slices.Sorted(maps.Keys(archive.Entries)) {
+		entry := archive.Entries[key]
+		if entry.AliasOf != "" || !fileExists(filepath.FromSlash(entry.Path)) {
+			continue
+		}
+		if len(wantedTypes) > 0 && !wantedTypes[entry.AssetType] {
+			continue
+		}
+		if product != "" && productSlug(productForEntry(entry)) != productSlug(product) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// writeBag builds a bag of the selected files at bagDir. The bag is assembled next to it and renamed
+// into place when complete, so a bag directory is never left half-written.
+func writeBag(bagDir string, archive *archiveManifest, selected []*manifestEntry) bool {
+	if _, err := os.Stat(bagDir); err == nil {
+		log.Printf("%s already exists; bags are never overwritten", bagDir)
+		return false
+	}
+	partialDir := filepath.Clean(bagDir) + extractTempSuffix
+	if err := os.RemoveAll(partialDir); err != nil {
+		log.Printf("Failed to clear %s: %v", partialDir, err)
+		return false
+	}
+	if err := os.MkdirAll(filepath.Join(partialDir, "data"), 0o755); err != nil {
+		log.Printf("Failed to create %s: %v", partialDir, err)
+		return false
+	}
+	failed := func(format string, values ...any) bool {
+		log.Printf(format, values...)
+		os.RemoveAll(partialDir)
+		return false
+	}
+
+	// Copy the payload, hashing it on the way, plus the manifest entries of exactly these files
+	payloadSums := make(map[string]map[string]string) // algorithm → payload path → checksum
+	subset := &archiveManifest{LastRun: archive.LastRun, Entries: make(map[string]*manifestEntry)}
+	var octets int64
+	for _, entry := range selected {
+		payloadPath := "data/" + entry.Path
+		sums, size, err := copyAndHash(filepath.FromSlash(entry.Path), filepath.Join(partialDir, filepath.FromSlash(payloadPath)))
+		if err != nil {
+			return failed("Failed to copy %s into the bag: %v", entry.Path, err)
+		}
+		if entry.SHA256 != "" && sums["sha256"] != entry.SHA256 {
+			return failed("%s does not match its manifest hash; run verify before bagging", entry.Path)
+		}
+		for algorithm, sum := range sums {
+			if payloadSums[algorithm] == nil {
+				payloadSums[algorithm] = make(map[string]string)
+			}
+			payloadSums[algorithm][payloadPath] = sum
+		}
+		octets += size
+		subset.Entries[entry.Path] = entry
+	}
+	data, err := json.MarshalIndent(subset, "", "  ")
+	if err != nil {
+		return failed("Failed to encode the bag's manifest: %v", err)
+	}
+	manifestCopy := "data/" + manifestPath
+	if err := os.WriteFile(filepath.Join(partialDir, filepath.FromSlash(manifestCopy)), append(data, '\n'), 0o644); err != nil {
+		return failed("Failed to write %s: %v", manifestCopy, err)
+	}
+	for algorithm, sum := range hashBytes(append(data, '\n')) {
+		payloadSums[algorithm][manifestCopy] = sum
+	}
+	octets += int64(len(data) + 1)
+	streams := len(selected) + 1
+
+	// Tag files: the declaration, the metadata and one payload manifest per algorithm
+	captured := archive.LastRun
+	if captured.IsZero() {
+		captured = time.Now().UTC()
+	}
+	seeds := currentSite.seeds()
+	sourceHost := ""
+	if len(seeds) > 0 {
+		if parsed, err := url.Parse(seeds[0]); err == nil {
+			sourceHost = parsed.Host
+		}
+	}
+	tagFiles := map[string]string{
+		"bagit.txt": "BagIt-Version: " + bagItVersion + "\nTag-File-Character-Encoding: UTF-8\n",
+		"bag-info.txt": bagInfoText([][2]string{
+			{"Source-Organization", currentSite.title() + " (" + sourceHost + ")"},
+			{"External-Description", "Snapshot of the files published on " + strings.Join(seeds, ", ")},
+			{"Source-Site", strings.Join(seeds, " ")},
+			{"Capture-Date", captured.Format(time.RFC3339)},
+			{"Bagging-Date", time.Now().Format("2006-01-02")},
+			{"Bag-Software-Agent", "caddx-archiver " + toolVersion()},
+			{"Payload-Oxum", fmt.Sprintf("%d.%d", octets, streams)},
+			{"Bag-Size", formatSize(octets)},
+		}),
+	}
+	for _, algorithm := range bagAlgorithms {
+		tagFiles["manifest-"+algorithm+".txt"] = bagManifestText(payloadSums[algorithm])
+	}
+	tagSums := make(map[string]map[string]string)
+	for name, contents := range tagFiles {
+		if err := os.WriteFile(filepath.Join(partialDir, name), []byte(contents), 0o644); err != nil {
+			return failed("Failed to write %s: %v", name, err)
+		}
+		for algorithm, sum := range hashBytes([]byte(contents)) {
+			if tagSums[algorithm] == nil {
+				tagSums[algorithm] = make(map[string]string)
+			}
+			tagSums[algorithm][name] = sum
+		}
+	}
+	for _, algorithm := range bagAlgorithms {
+		name := "tagmanifest-" + algorithm + ".txt"
+		if err := os.WriteFile(filepath.Join(partialDir, name), []byte(bagManifestText(tagSums[algorithm])), 0o644); err != nil {
+			return failed("Failed to write %s: %v", name, err)
+		}
+	}
+
+	if err := os.Rename(partialDir, bagDir); err != nil {
+		return failed("Failed to move the bag into %s: %v", bagDir, err)
+	}
+	log.Printf("Wrote bag %s with %d files (%s)", bagDir, streams, formatSize(octets))
+	return true
+}
+
+// copyAndHash copies a file and returns its checksums for every bag algorithm and its size.
+func copyAndHash(source, destination string) (map[string]string, int64, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return nil, 0, err
+	}
+	out, err := os.Create(destination)
+	if err != nil {
+		return nil, 0, err
+	}
+	hashes := make(map[string]hash.Hash)
+	writers := []io.Writer{out}
+	for _, algorithm := range bagAlgorithms {
+		hashes[algorithm] = bagHashes[algorithm]()
+		writers = append(writers, hashes[algorithm])
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	// Keep the original modification time in the payload
+	if info, err := in.Stat(); err == nil {
+		os.Chtimes(destination, info.ModTime(), info.ModTime())
+	}
+	sums := make(map[string]string)
+	for algorithm, hasher := range hashes {
+		sums[algorithm] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return sums, size, nil
+}
+
+// hashBytes returns the checksums of the data for every bag algorithm.
+func hashBytes(data []byte) map[string]string {
+	sums := make(map[string]string)
+	for _, algorithm := range bagAlgorithms {
+		hasher := bagHashes[algorithm]()
+		hasher.Write(data)
+		sums[algorithm] = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return sums
+}
+
+// bagInfoText formats bag-info.txt metadata elements in the given order, skipping empty values.
+func bagInfoText(elements [][2]string) string {
+	var text strings.Builder
+	for _, element := range elements {
+		if element[1] != "" {
+			text.WriteString(element[0] + ": " + element[1] + "\n")
+		}
+	}
+	return text.String()
+}
+
+// bagManifestText formats a manifest: one "checksum  path" line per file, sorted by path.
+func bagManifestText(sums map[string]string) string {
+	var text strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		text.WriteString(sums[name] + "  " + encodeBagPath(name) + "\n")
+	}
+	return text.String()
+}
+
+// encodeBagPath percent-encodes the characters RFC 8493 does not allow literally in manifest paths.
+func encodeBagPath(name string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(name)
+}
+
+// decodeBagPath reverses encodeBagPath.
+func decodeBagPath(name string) string {
+	return strings.NewReplacer("%0D", "\r", "%0d", "\r", "%0A", "\n", "%0a", "\n", "%25", "%").Replace(name)
+}
+
+// toolVersion returns the version control revision the archiver was built from, or "(devel)".
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return info.Main.Version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// runBagValidate implements "bag validate": check that a bag is complete and every checksum matches.
+func runBagValidate(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("bag validate", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: bag validate <bag directory>")
+		return 2
+	}
+	problems := validateBag(flags.Arg(0))
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%s is not a valid bag (%d problems)\n", flags.Arg(0), len(problems))
+		return 1
+	}
+	fmt.Printf("%s is a valid bag\n", flags.Arg(0))
+	return 0
+}
+
+// validateBag checks a bag as RFC 8493 section 3 describes and returns every problem found:
+// the declaration, a complete payload manifest for each algorithm, every checksum, and Payload-Oxum.
+func validateBag(bagDir string) []string {
+	var problems []string
+	declaration, err := readBagTagFile(filepath.Join(bagDir, "bagit.txt"))
+	if err != nil {
+		return []string{fmt.Sprintf("bagit.txt: %v", err)}
+	}
+	if declaration["BagIt-Version"] == "" {
+		problems = append(problems, "bagit.txt: missing BagIt-Version")
+	}
+	if encoding := declaration["Tag-File-Character-Encoding"]; !strings.EqualFold(encoding, "UTF-8") {
+		problems = append(problems, fmt.Sprintf("bagit.txt: unsupported tag file encoding %q", encoding))
+	}
+
+	// Every file in data/ must be listed in every payload manifest
+	payload := make(map[string]int64)
+	err = filepath.WalkDir(filepath.Join(bagDir, "data"), func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(bagDir, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		payload[filepath.ToSlash(relative)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("data/: %v", err))
+	}
+
+	manifests, _ := filepath.Glob(filepath.Join(bagDir, "manifest-*.txt"))
+	if len(manifests) == 0 {
+		problems = append(problems, "no payload manifest")
+	}
+	for _, manifestFile := range manifests {
+		algorithm := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(manifestFile), "manifest-"), ".txt")
+		listed, manifestProblems := checkBagManifest(bagDir, manifestFile, algorithm)
+		problems = append(problems, manifestProblems...)
+		for _, name := range slices.Sorted(maps.Keys(payload)) {
+			if !listed[name] {
+				problems = append(problems, fmt.Sprintf("%s: not listed in %s", name, filepath.Base(manifestFile)))
+			}
+		}
+	}
+	tagManifests, _ := filepath.Glob(filepath.Join(bagDir, "tagmanifest-*.txt"))
+	for _, manifestFile := range tagManifests {
+		algorithm := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(manifestFile), "tagmanifest-"), ".txt")
+		_, manifestProblems := checkBagManifest(bagDir, manifestFile, algorithm)
+		problems = append(problems, manifestProblems...)
+	}
+
+	// Payload-Oxum is a quick size and count check, when bag-info.txt declares it
+	if info, err := readBagTagFile(filepath.Join(bagDir, "bag-info.txt")); err == nil && info["Payload-Oxum"] != "" {
+		octetsText, streamsText, _ := strings.Cut(info["Payload-Oxum"], ".")
+		octets, octetsErr := strconv.ParseInt(octetsText, 10, 64)
+		streams, streamsErr := strconv.Atoi(streamsText)
+		var actualOctets int64
+		for _, size := range payload {
+			actualOctets += size
+		}
+		switch {
+		case octetsErr != nil || streamsErr != nil:
+			problems = append(problems, fmt.Sprintf("bag-info.txt: malformed Payload-Oxum %q", info["Payload-Oxum"]))
+		case octets != actualOctets || streams != len(payload):
+			problems = append(problems, fmt.Sprintf("bag-info.txt: Payload-Oxum %s but the payload is %d.%d", info["Payload-Oxum"], actualOctets, len(payload)))
+		}
+	}
+	return problems
+}
+
+// checkBagManifest verifies every line of a payload or tag manifest and returns the paths it lists.
+func checkBagManifest(bagDir, manifestFile, algorithm string) (map[string]bool, []string) {
+	name := filepath.Base(manifestFile)
+	newHash := bagHashes[algorithm]
+	if newHash == nil {
+		return nil, []string{fmt.Sprintf("%s: unsupported algorithm %q", name, algorithm)}
+	}
+	file, err := os.Open(manifestFile)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("%s: %v", name, err)}
+	}
+	defer file.Close()
+
+	listed := make(map[string]bool)
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sum, listedPath, found := strings.Cut(line, " ")
+		listedPath = decodeBagPath(strings.TrimLeft(listedPath, " \t"))
+		if !found || listedPath == "" {
+			problems = append(problems, fmt.Sprintf("%s:%d: malformed line", name, lineNumber))
+			continue
+		}
+		if !validStorageName(listedPath) {
+			problems = append(problems, fmt.Sprintf("%s:%d: path %q escapes the bag", name, lineNumber, listedPath))
+			continue
+		}
+		listed[listedPath] = true
+		actual, err := hashFileWith(filepath.Join(bagDir, filepath.FromSlash(listedPath)), newHash)
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, fmt.Sprintf("%s: missing (listed in %s)", listedPath, name))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", listedPath, err))
+		case !strings.EqualFold(actual, sum):
+			problems = append(problems, fmt.Sprintf("%s: %s checksum mismatch", listedPath, algorithm))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+	}
+	return listed, problems
+}
+
+// hashFileWith returns the hex digest of a file with the given hash.
+func hashFileWith(filePath string, newHash func() hash.Hash) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := newHash()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// readBagTagFile reads the "Label: value" elements of bagit.txt or bag-info.txt.
+// Continuation lines (starting with whitespace) are joined to the previous value.
+func readBagTagFile(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	elements := make(map[string]string)
+	lastLabel := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lastLabel != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			elements[lastLabel] += " " + strings.TrimSpace(line)
+			continue
+		}
+		label, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		lastLabel = strings.TrimSpace(label)
+		if _, repeated := elements[lastLabel]; !repeated {
+			elements[lastLabel] = strings.TrimSpace(value)
+		}
+	}
+	return elements, scanner.Err()
+}
@@ -0,0 +1,44 @@
+package main // Define the main package
+
+import "slices" // Provides slice concatenation
+
+// caddxSite is the built-in adapter for the Caddx FPV download center, which also hosts the Walksnail files.
+// Its archive lives at the repository root.
+var caddxSite = &shopifySite{
+	Name:  "caddx",
+	Title: "Caddx FPV",
+	Seeds: []string{
+		"https://caddxfpv.com/pages/download-center",
+	},
+	Hosts:    []string{"caddxfpv.com"},
+	Products: caddxProductRules,
+}
+
+// caddxProductRules are tried in order, so more specific products come before their families.
+// The download center also lists the Walksnail products, which are matched first.
+var caddxProductRules = slices.Concat(walksnailProductRules, []productRule{
+	{Name: "Baby Ratel 2", Patterns: [][]string{{"baby", "ratel2"}, {"baby", "ratel", "2"}}},
+	{Name: "Ratel 2", Patterns: [][]string{{"ratel2"}, {"ratel", "2"}}},
+	{Name: "Ratel Pro", Patterns: [][]string{{"ratel", "pro"}}},
+	{Name: "Ratel", Patterns: [][]string{{"ratel"}}},
+	{Name: "Baby Turtle", Patterns: [][]string{{"baby", "turtle"}}},
+	{Name: "Turtle", Patterns: [][]string{{"turtle"}}},
+	{Name: "GoFilm 20", Patterns: [][]string{{"gofilm", "20"}, {"gofilm20"}}},
+	{Name: "GoFilm", Patterns: [][]string{{"gofilm"}}},
+	{Name: "Infra", Patterns: [][]string{{"infra"}}},
+	{Name: "Loris", Patterns: [][]string{{"loris"}}},
+	{Name: "Nebula Pro Nano", Patterns: [][]string{{"nebula", "pro", "nano"}}},
+	{Name: "Nebula Pro", Patterns: [][]string{{"nebula", "pro"}}},
+	{Name: "Nebula", Patterns: [][]string{{"nebula"}}},
+	{Name: "Protos", Patterns: [][]string{{"protos"}}},
+	{Name: "Gazer", Patterns: [][]string{{"gazer"}}},
+	{Name: "Farsight", Patterns: [][]string{{"farsight"}}},
+	{Name: "Vista", Patterns: [][]string{{"vista"}}},
+	{Name: "GM Gimbal", Patterns: [][]string{{"gm", "gimbal"}, {"gm"}}},
+	{Name: "Kangaroo", Patterns: [][]string{{"kangaroo"}}},
+	{Name: "Dolphin", Patterns: [][]string{{"dolphin"}}},
+	{Name: "Orca", Patterns: [][]string{{"orca"}}},
+	{Name: "Tarsier", Patterns: [][]string{{"tarsier"}}},
+	{Name: "Walnut", Patterns: [][]string{{"walnut"}}},
+	{Name: "Repeater", Patterns: [][]string{{"repeater"}}},
+})
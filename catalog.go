@@ -0,0 +1,316 @@
+package main // Define the main package
+
+import (
+	"encoding/json" // Provides JSON encoding for catalog.json
+	"fmt"           // Provides formatted output for the Markdown pages
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path"          // Provides functions for manipulating slash-separated paths
+	"path/filepath" // Provides filepath manipulation functions
+	"regexp"        // Provides regex support functions.
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+)
+
+// catalogPath is where the product catalog is written, next to the manifest.
+const catalogPath = "catalog.json"
+
+// catalogPagesDir holds one Markdown page per product plus an index.
+const catalogPagesDir = "catalog/"
+
+// otherProductName collects files that no heuristic could place.
+const otherProductName = "Other"
+
+// catalogCategory is a group of asset types shown together on a product page.
+type catalogCategory struct {
+	Key   string // Key used in catalog.json (matches assetType.Category)
+	Title string // Heading used on the Markdown pages
+}
+
+// catalogCategories lists the product page sections in display order.
+var catalogCategories = []catalogCategory{
+	{Key: "manuals", Title: "Manuals"},
+	{Key: "images", Title: "Images"},
+	{Key: "models", Title: "3D Models"},
+	{Key: "firmware", Title: "Firmware & Archives"},
+}
+
+// productRule names a product and the filename/text token sequences that identify it.
+type productRule struct {
+	Name     string     // Display name of the product
+	Patterns [][]string // Any of these consecutive token sequences identifies the product
+}
+
+// productTokenRegex splits names and text into lowercase alphanumeric tokens.
+var productTokenRegex = regexp.MustCompile(`[a-z0-9]+`)
+
+// productCatalog groups every archived file by product.
+type productCatalog struct {
+	Products []*catalogProduct `json:"products"`
+}
+
+// catalogProduct lists the archived files of one product by category.
+type catalogProduct struct {
+	Name  string                   `json:"name"`
+	Slug  string                   `json:"slug"`
+	Files map[string][]catalogFile `json:"files"`
+}
+
+// catalogFile is one archived file as shown in the catalog.
+type catalogFile struct {
+	Path      string   `json:"path"`
+	URL       string   `json:"url,omitempty"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256,omitempty"`
+	Preview   string   `json:"preview,omitempty"`   // Rendered preview image of 3D models
+	Languages []string `json:"languages,omitempty"` // Languages of documents
+}
+
+// productTokens returns the lowercase alphanumeric tokens of a name or text.
+func productTokens(text string) []string {
+	return productTokenRegex.FindAllString(strings.ToLower(text), -1)
+}
+
+// matchProductRule returns the first product rule whose pattern appears in the tokens, or "".
+// Long patterns also match inside run-together names such as "walksnailavatarhdgogglesx".
+func matchProductRule(rules []productRule, tokens []string) string {
+	compact := strings.Join(tokens, "")
+	for _, rule := range rules {
+		for _, pattern := range rule.Patterns {
+			if containsTokenSequence(tokens, pattern) {
+				return rule.Name
+			}
+			if compactPattern := strings.Join(pattern, ""); len(compactPattern) >= 6 && strings.Contains(compact, compactPattern) {
+				return rule.Name
+			}
+		}
+	}
+	return ""
+}
+
+// containsTokenSequence reports whether the pattern appears as consecutive tokens.
+func containsTokenSequence(tokens, pattern []string) bool {
+	for start := 0; start+len(pattern) <= len(tokens); start++ {
+		if slices.Equal(tokens[start:start+len(pattern)], pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// productForEntry decides which product an archived file belongs to, using the selected site's heuristics.
+func productForEntry(entry *manifestEntry) string {
+	return currentSite.product(entry)
+}
+
+// productFromRules groups a file by the product rules of a site.
+// Shopify product data wins, then the filename tokens, then the link text and heading.
+func productFromRules(entry *manifestEntry, rules []productRule) string {
+	// Shopify product data names the product directly
+	for _, context := range entry.Contexts {
+		if context.Product != "" {
+			return context.Product
+		}
+	}
+	// Known product names in the file name
+	if product := matchProductRule(rules, productTokens(path.Base(entry.Path))); product != "" {
+		return product
+	}
+	// Known product names in the link text, image alt text, title or heading
+	for _, context := range entry.Contexts {
+		for _, text := range []string{context.AnchorText, context.Alt, context.Title, context.Heading} {
+			if product := matchProductRule(rules, productTokens(text)); product != "" {
+				return product
+			}
+		}
+	}
+	// Fall back to the closest heading as the product name
+	for _, context := range entry.Contexts {
+		if context.Heading != "" {
+			return context.Heading
+		}
+	}
+	return otherProductName
+}
+
+// productSlug turns a product name into a lowercase, dash-separated file name.
+func productSlug(name string) string {
+	slug := strings.Join(productTokens(name), "-")
+	if slug == "" {
+		return "other"
+	}
+	return slug
+}
+
+// buildCatalog groups the manifest entries into products, sorted by name with "Other" last.
+func buildCatalog(archive *archiveManifest) *productCatalog {
+	productsBySlug := make(map[string]*catalogProduct)
+	for _, key := range slices.Sorted(maps.Keys(archive.Entries)) {
+		entry := archive.Entries[key]
+		asset := assetTypeByExtension(filepath.Ext(entry.Path))
+		if asset == nil || entry.AliasOf != "" {
+			continue // Aliased images are represented by the copy that was kept
+		}
+		name := productForEntry(entry)
+		slug := productSlug(name)
+		product, found := productsBySlug[slug]
+		if !found {
+			product = &catalogProduct{Name: name, Slug: slug, Files: make(map[string][]catalogFile)}
+			productsBySlug[slug] = product
+		}
+		product.Files[asset.Category] = append(product.Files[asset.Category], catalogFile{
+			Path:      entry.Path,
+			URL:       entry.URL,
+			Size:      entry.Size,
+			SHA256:    entry.SHA256,
+			Preview:   entry.Preview,
+			Languages: entry.Languages,
+		})
+	}
+
+	// Sort products by name, keeping the catch-all group at the end
+	catalog := &productCatalog{}
+	for _, product := range productsBySlug {
+		catalog.Products = append(catalog.Products, product)
+	}
+	slices.SortFunc(catalog.Products, func(first, second *catalogProduct) int {
+		if (first.Name == otherProductName) != (second.Name == otherProductName) {
+			if first.Name == otherProductName {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(strings.ToLower(first.Name), strings.ToLower(second.Name))
+	})
+	return catalog
+}
+
+// writeCatalog writes catalog.json and one Markdown page per product, removing pages of products that are gone.
+func writeCatalog(catalog *productCatalog) bool {
+	// Write catalog.json
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode catalog: %v", err)
+		return false
+	}
+	if err := os.WriteFile(catalogPath, append(data, '\n'), 0o644); err != nil {
+		log.Printf("Failed to write catalog %s: %v", catalogPath, err)
+		return false
+	}
+
+	// Check if the catalog pages directory exists
+	if !directoryExists(catalogPagesDir) {
+		// Create the dir
+		createDirectory(catalogPagesDir, 0o755)
+	}
+
+	// Write the index and one page per product
+	writtenPages := map[string]bool{"README.md": true}
+	if err := os.WriteFile(filepath.Join(catalogPagesDir, "README.md"), []byte(renderCatalogIndex(catalog)), 0o644); err != nil {
+		log.Printf("Failed to write catalog index: %v", err)
+		return false
+	}
+	for _, product := range catalog.Products {
+		pageName := product.Slug + ".md"
+		writtenPages[pageName] = true
+		if err := os.WriteFile(filepath.Join(catalogPagesDir, pageName), []byte(renderProductPage(product)), 0o644); err != nil {
+			log.Printf("Failed to write catalog page %s: %v", pageName, err)
+			return false
+		}
+	}
+
+	// Remove pages left over from products that no longer exist
+	pages, err := os.ReadDir(catalogPagesDir)
+	if err != nil {
+		log.Printf("Failed to list %s: %v", catalogPagesDir, err)
+		return false
+	}
+	for _, page := range pages {
+		if !page.IsDir() && strings.HasSuffix(page.Name(), ".md") && !writtenPages[page.Name()] {
+			if err := os.Remove(filepath.Join(catalogPagesDir, page.Name())); err != nil {
+				log.Printf("Failed to remove stale catalog page %s: %v", page.Name(), err)
+			}
+		}
+	}
+	log.Printf("Wrote catalog with %d products to %s and %s", len(catalog.Products), catalogPath, catalogPagesDir)
+	return true
+}
+
+// renderCatalogIndex renders the Markdown index listing every product.
+func renderCatalogIndex(catalog *productCatalog) string {
+	var page strings.Builder
+	page.WriteString("# Product Catalog\n\n")
+	page.WriteString("| Product | Manuals | Images | 3D Models | Firmware & Archives |\n")
+	page.WriteString("|---|---|---|---|---|\n")
+	for _, product := range catalog.Products {
+		fmt.Fprintf(&page, "| [%s](%s.md) |", escapeMarkdown(product.Name), product.Slug)
+		for _, category := range catalogCategories {
+			fmt.Fprintf(&page, " %d |", len(product.Files[category.Key]))
+		}
+		page.WriteString("\n")
+	}
+	return page.String()
+}
+
+// renderProductPage renders the Markdown page of one product with a table per category.
+func renderProductPage(product *catalogProduct) string {
+	var page strings.Builder
+	fmt.Fprintf(&page, "# %s\n", escapeMarkdown(product.Name))
+	for _, category := range catalogCategories {
+		files := product.Files[category.Key]
+		if len(files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&page, "\n## %s\n\n", category.Title)
+		// Categories with rendered models get a preview column
+		withPreviews := slices.ContainsFunc(files, func(file catalogFile) bool { return file.Preview != "" })
+		if withPreviews {
+			page.WriteString("| Preview | File | Size | Source |\n")
+			page.WriteString("|---|---|---|---|\n")
+		} else {
+			page.WriteString("| File | Size | Source |\n")
+			page.WriteString("|---|---|---|\n")
+		}
+		for _, file := range files {
+			// Pages live one directory below the archive root
+			source := ""
+			if file.URL != "" {
+				source = fmt.Sprintf("[source](%s)", file.URL)
+			}
+			if withPreviews {
+				preview := ""
+				if file.Preview != "" {
+					preview = fmt.Sprintf("![preview](../%s)", file.Preview)
+				}
+				fmt.Fprintf(&page, "| %s ", preview)
+			}
+			languages := ""
+			if len(file.Languages) > 0 && !slices.Equal(file.Languages, []string{undeterminedLanguage}) {
+				languages = " (" + strings.Join(file.Languages, ", ") + ")"
+			}
+			fmt.Fprintf(&page, "| [%s](../%s)%s | %s | %s |\n", escapeMarkdown(path.Base(file.Path)), file.Path, languages, formatSize(file.Size), source)
+		}
+	}
+	return page.String()
+}
+
+// escapeMarkdown escapes the characters that would break a Markdown table cell or link text.
+func escapeMarkdown(text string) string {
+	replacer := strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`)
+	return replacer.Replace(text)
+}
+
+// formatSize renders a byte count as a short human-readable size.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	divisor, exponent := int64(unit), 0
+	for remaining := size / unit; remaining >= unit; remaining /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(divisor), "KMGTPE"[exponent])
+}
@@ -0,0 +1,242 @@
+package main // Define the main package
+
+import (
+	"bufio"           // Provides line reading of SHA256SUMS
+	"bytes"           // Provides byte slice helpers
+	"crypto/ed25519"  // Provides the signatures
+	"crypto/rand"     // Provides key generation
+	"crypto/sha512"   // Provides the secret key checksum of the signify format
+	"encoding/base64" // Provides the signify file encoding
+	"errors"          // Provides error values
+	"flag"            // Provides command line flag parsing
+	"fmt"             // Provides formatted output
+	"log"             // Provides logging functions
+	"maps"            // Provides map iteration helpers
+	"os"              // Provides functions to interact with the OS (files, etc.)
+	"path/filepath"   // Provides filepath manipulation functions
+	"slices"          // Provides slice sorting helpers
+	"strings"         // Provides string manipulation functions
+)
+
+// Release checksum files, next to the manifest.
+const (
+	checksumsPath = "SHA256SUMS"     // sha256sum-compatible list of every archived file
+	signaturePath = "SHA256SUMS.sig" // Detached signify signature of SHA256SUMS
+	publicKeyPath = "release.pub"    // signify public key people verify releases with
+	secretKeyPath = "release.sec"    // signify secret key; never committed
+)
+
+// signifyAlgorithm marks Ed25519 keys and signatures in the signify formats.
+var signifyAlgorithm = []byte("Ed")
+
+// runChecksums implements the checksums command: "keygen" creates a signing key pair, "sign" writes and
+// signs SHA256SUMS, and "verify" checks the signature and every file it lists.
+// Keys and signatures use OpenBSD signify's formats, so "signify -V -p release.pub -x SHA256SUMS.sig
+// -m SHA256SUMS" and "sha256sum -c SHA256SUMS" work without this tool.
+func runChecksums(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: checksums keygen | sign | verify [flags]")
+		return 2
+	}
+	flags := flag.NewFlagSet("checksums "+args[0], flag.ExitOnError)
+	secretKey := flags.String("key", secretKeyPath, "signify secret key file")
+	publicKey := flags.String("pubkey", publicKeyPath, "signify public key file")
+	flags.Parse(args[1:])
+	switch args[0] {
+	case "keygen":
+		return generateSigningKeys(*secretKey, *publicKey)
+	case "sign":
+		return signChecksums(*secretKey)
+	case "verify":
+		return verifyChecksums(*publicKey)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown checksums action %q (expected keygen, sign or verify)\n", args[0])
+		return 2
+	}
+}
+
+// generateSigningKeys writes a new unencrypted signify key pair. Existing keys are never overwritten.
+func generateSigningKeys(secretKeyFile, publicKeyFile string) int {
+	for _, keyFile := range []string{secretKeyFile, publicKeyFile} {
+		if fileExists(keyFile) {
+			log.Printf("%s already exists; remove it first to create a new key", keyFile)
+			return 1
+		}
+	}
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Printf("Failed to generate a key: %v", err)
+		return 1
+	}
+	keyNumber := make([]byte, 8)
+	salt := make([]byte, 16)
+	rand.Read(keyNumber)
+	rand.Read(salt)
+
+	// Secret key: algorithm, KDF "BK" with 0 rounds (no passphrase), salt, checksum, key number, key
+	checksum := sha512.Sum512(privateKey)
+	secret := slices.Concat(signifyAlgorithm, []byte("BK"), []byte{0, 0, 0, 0}, salt, checksum[:8], keyNumber, privateKey)
+	if err := writeSignifyFile(secretKeyFile, "signify secret key", secret, 0o600); err != nil {
+		log.Printf("Failed to write %s: %v", secretKeyFile, err)
+		return 1
+	}
+	public := slices.Concat(signifyAlgorithm, keyNumber, publicKey)
+	if err := writeSignifyFile(publicKeyFile, "signify public key", public, 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", publicKeyFile, err)
+		return 1
+	}
+	log.Printf("Wrote %s (keep it private) and %s (publish it)", secretKeyFile, publicKeyFile)
+	return 0
+}
+
+// signChecksums hashes every archived file into SHA256SUMS and signs it with the secret key.
+// Files that no longer match the manifest are refused, so damage is never signed as genuine.
+func signChecksums(secretKeyFile string) int {
+	secret, err := readSignifyFile(secretKeyFile)
+	if err != nil {
+		log.Printf("Failed to read %s: %v", secretKeyFile, err)
+		return 1
+	}
+	// 2 algorithm + 2 KDF + 4 rounds + 16 salt + 8 checksum + 8 key number + 64 key
+	if len(secret) != 104 || !bytes.Equal(secret[:2], signifyAlgorithm) {
+		log.Printf("%s is not a signify Ed25519 secret key", secretKeyFile)
+		return 1
+	}
+	if !bytes.Equal(secret[4:8], []byte{0, 0, 0, 0}) {
+		log.Printf("%s is protected by a passphrase, which is not supported; create one with \"signify -G -n\" or \"checksums keygen\"", secretKeyFile)
+		return 1
+	}
+	keyNumber, privateKey := secret[32:40], ed25519.PrivateKey(secret[40:104])
+	if checksum := sha512.Sum512(privateKey); !bytes.Equal(checksum[:8], secret[24:32]) {
+		log.Printf("%s is corrupt (checksum mismatch)", secretKeyFile)
+		return 1
+	}
+
+	archive := loadManifest(manifestPath)
+	var sums bytes.Buffer
+	count := 0
+	for _, key := range slices.Sorted(maps.Keys(archive.Entries)) {
+		entry := archive.Entries[key]
+		if entry.AliasOf != "" {
+			continue
+		}
+		_, sum, err := hashFile(filepath.FromSlash(key))
+		if err != nil {
+			log.Printf("Skipping %s: %v", key, err)
+			continue
+		}
+		if entry.SHA256 != "" && sum != entry.SHA256 {
+			log.Printf("%s no longer matches the manifest; run verify and fix it before signing", key)
+			return 1
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, key)
+		count++
+	}
+
+	signature := slices.Concat(signifyAlgorithm, keyNumber, ed25519.Sign(privateKey, sums.Bytes()))
+	if err := os.WriteFile(checksumsPath, sums.Bytes(), 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", checksumsPath, err)
+		return 1
+	}
+	if err := writeSignifyFile(signaturePath, "verify with "+filepath.Base(strings.TrimSuffix(secretKeyFile, ".sec"))+".pub", signature, 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", signaturePath, err)
+		return 1
+	}
+	log.Printf("Signed %s with %d files", checksumsPath, count)
+	return 0
+}
+
+// verifyChecksums checks the signature of SHA256SUMS and then every file it lists, and reports files
+// that are missing, modified, or present in the type folders without being listed.
+func verifyChecksums(publicKeyFile string) int {
+	public, err := readSignifyFile(publicKeyFile)
+	if err != nil {
+		log.Printf("Failed to read %s: %v", publicKeyFile, err)
+		return 1
+	}
+	if len(public) != 42 || !bytes.Equal(public[:2], signifyAlgorithm) {
+		log.Printf("%s is not a signify Ed25519 public key", publicKeyFile)
+		return 1
+	}
+	sums, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		log.Printf("Failed to read %s: %v", checksumsPath, err)
+		return 1
+	}
+	signature, err := readSignifyFile(signaturePath)
+	if err != nil {
+		log.Printf("Failed to read %s: %v", signaturePath, err)
+		return 1
+	}
+
+	exitCode := 0
+	switch {
+	case len(signature) != 74 || !bytes.Equal(signature[:2], signifyAlgorithm):
+		fmt.Printf("BAD SIGNATURE %s: not a signify Ed25519 signature\n", signaturePath)
+		exitCode = 1
+	case !bytes.Equal(signature[2:10], public[2:10]):
+		fmt.Printf("BAD SIGNATURE %s: made with a different key than %s\n", signaturePath, publicKeyFile)
+		exitCode = 1
+	case !ed25519.Verify(ed25519.PublicKey(public[10:42]), sums, signature[10:74]):
+		fmt.Printf("BAD SIGNATURE %s: %s was altered after signing\n", signaturePath, checksumsPath)
+		exitCode = 1
+	default:
+		fmt.Printf("Signature OK: %s\n", checksumsPath)
+	}
+
+	listed := make(map[string]bool)
+	missing, modified, verified := 0, 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		sum, listedPath, found := strings.Cut(scanner.Text(), "  ")
+		if !found {
+			continue
+		}
+		listed[listedPath] = true
+		_, actual, err := hashFile(filepath.FromSlash(listedPath))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			fmt.Printf("MISSING  %s\n", listedPath)
+			missing++
+		case err != nil:
+			fmt.Printf("MISSING  %s (%v)\n", listedPath, err)
+			missing++
+		case actual != sum:
+			fmt.Printf("MODIFIED %s\n", listedPath)
+			modified++
+		default:
+			verified++
+		}
+	}
+	extra := 0
+	for _, onDisk := range archiveFilesOnDisk() {
+		if !listed[onDisk] {
+			fmt.Printf("EXTRA    %s\n", onDisk)
+			extra++
+		}
+	}
+	fmt.Printf("%d files verified, %d missing, %d modified, %d extra\n", verified, missing, modified, extra)
+	if missing > 0 || modified > 0 || extra > 0 {
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// writeSignifyFile writes data in signify's two-line format: an untrusted comment and the base64 data.
+func writeSignifyFile(filePath, comment string, data []byte, permission os.FileMode) error {
+	contents := "untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(data) + "\n"
+	return os.WriteFile(filePath, []byte(contents), permission)
+}
+
+// readSignifyFile returns the base64-decoded data line of a signify key or signature file.
+func readSignifyFile(filePath string) ([]byte, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitN(strings.ReplaceAll(string(contents), "\r\n", "\n"), "\n", 3)
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "untrusted comment: ") {
+		return nil, errors.New("not a signify file (missing untrusted comment)")
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+}
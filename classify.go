@@ -0,0 +1,156 @@
+package main // Define the main package
+
+import (
+	"mime"    // Provides Content-Disposition header parsing
+	"net/url" // Provides URL parsing and encoding
+	"path"    // Provides functions for manipulating slash-separated paths
+	"strings" // Provides string manipulation functions
+)
+
+// assetType describes one kind of archived file: where it is stored,
+// which path extensions identify it and which content types the server may send for it.
+type assetType struct {
+	Name         string         // Short lowercase name (e.g. "pdf")
+	Label        string         // Uppercase label used in log messages (e.g. "PDF")
+	OutputDir    string         // Directory the files are stored in
+	Category     string         // Catalog category (manuals, images, models or firmware)
+	Extensions   []string       // Lowercase path extensions including the dot
+	LinkTags     []string       // Page tags whose link is collected: "a" (href) for downloads, "img" (src) for pictures
+	ContentTypes []string       // Accepted Content-Type values
+	Validator    assetValidator // Checks downloaded files before they are saved
+}
+
+// assetTypes lists every asset type in download order.
+// Each extension belongs to exactly one type, so every URL gets at most one type.
+var assetTypes = []assetType{
+	{
+		Name:         "pdf",
+		Label:        "PDF",
+		OutputDir:    "PDFs/",
+		Category:     "manuals",
+		Extensions:   []string{".pdf"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"application/pdf"},
+		Validator:    pdfValidator{},
+	},
+	{
+		Name:         "stp",
+		Label:        "STP",
+		OutputDir:    "STPs/",
+		Category:     "models",
+		Extensions:   []string{".stp"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"model/step", "application/octet-stream", "application/step"},
+		Validator:    stepValidator{},
+	},
+	{
+		Name:         "stl",
+		Label:        "STL",
+		OutputDir:    "STLs/",
+		Category:     "models",
+		Extensions:   []string{".stl"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"application/vnd.ms-pki.stl"},
+		Validator:    stlValidator{},
+	},
+	{
+		Name:         "zip",
+		Label:        "ZIP",
+		OutputDir:    "ZIPs/",
+		Category:     "firmware",
+		Extensions:   []string{".zip"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"application/zip", "application/x-zip-compressed", "application/octet-stream"},
+		Validator:    zipValidator{},
+	},
+	{
+		Name:         "jpg",
+		Label:        "JPG",
+		OutputDir:    "JPGs/",
+		Category:     "images",
+		Extensions:   []string{".jpg", ".jpeg"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"image/jpeg", "image/jpg"},
+		Validator:    imageValidator{Format: "jpeg"},
+	},
+	{
+		Name:         "rar",
+		Label:        "RAR",
+		OutputDir:    "RARs/",
+		Category:     "firmware",
+		Extensions:   []string{".rar"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"application/x-rar-compressed", "application/octet-stream"},
+		Validator:    rarValidator{},
+	},
+	{
+		Name:         "png",
+		Label:        "PNG",
+		OutputDir:    "PNGs/",
+		Category:     "images",
+		Extensions:   []string{".png"},
+		LinkTags:     []string{"img"},
+		ContentTypes: []string{"image/png"},
+		Validator:    imageValidator{Format: "png"},
+	},
+	{
+		Name:         "step",
+		Label:        "STEP",
+		OutputDir:    "STEPs/",
+		Category:     "models",
+		Extensions:   []string{".step"},
+		LinkTags:     []string{"a"},
+		ContentTypes: []string{"application/step", "application/sla", "application/octet-stream"},
+		Validator:    stepValidator{},
+	},
+}
+
+// assetTypeByExtension returns the asset type that owns the given extension, or nil if none does.
+func assetTypeByExtension(extension string) *assetType {
+	extension = strings.ToLower(extension)
+	for index := range assetTypes {
+		for _, candidate := range assetTypes[index].Extensions {
+			if candidate == extension {
+				return &assetTypes[index]
+			}
+		}
+	}
+	return nil
+}
+
+// classifyAssetURL parses the URL and returns the asset type of its path extension, or nil.
+// Query strings and fragments are ignored, so "foo.pdf.html" and "page?file=a.zip" are not assets.
+func classifyAssetURL(rawURL string) *assetType {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil // Unparseable URLs are never assets
+	}
+	return assetTypeByExtension(path.Ext(parsedURL.Path))
+}
+
+// classifyContentDisposition returns the asset type of the filename in a Content-Disposition header, or nil.
+// It is used as a hint when the server names the file differently from the URL path.
+func classifyContentDisposition(header string) (*assetType, string) {
+	if header == "" {
+		return nil, ""
+	}
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, "" // Malformed headers give no hint
+	}
+	filename := path.Base(strings.ReplaceAll(params["filename"], `\`, "/"))
+	if filename == "" || filename == "." || filename == "/" {
+		return nil, ""
+	}
+	return assetTypeByExtension(path.Ext(filename)), filename
+}
+
+// acceptsContentType reports whether the response Content-Type is one the asset type expects.
+func (asset *assetType) acceptsContentType(contentType string) bool {
+	for _, expected := range asset.ContentTypes {
+		if strings.Contains(contentType, expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main // Define the main package
+
+import (
+	"fmt"     // Provides formatted output for the usage text
+	"maps"    // Provides map iteration helpers
+	"os"      // Provides functions to interact with the OS (files, etc.)
+	"slices"  // Provides slice sorting helpers
+	"strings" // Provides string manipulation functions
+)
+
+// command is one subcommand of the archiver.
+type command struct {
+	Run     func(args []string) int // Runs the command and returns the exit code
+	Summary string                  // One-line description for the usage text
+}
+
+// defaultCommand runs when no subcommand is given (e.g. "go run ." in the workflow).
+const defaultCommand = "scrape"
+
+// commands lists every subcommand by name.
+var commands = map[string]command{
+	"bag":        {Run: runBag, Summary: "package the archive or a filtered subset as a BagIt bag; \"bag validate <bag>\" checks one"},
+	"checksums":  {Run: runChecksums, Summary: "keygen, sign or verify the signed SHA256SUMS of every archived file (signify format)"},
+	"duplicates": {Run: runDuplicates, Summary: "list near-duplicate images; -apply keeps the largest and records the rest as aliases"},
+	"inspect":    {Run: runInspect, Summary: "validate files and print their details (STL dimensions, volume, manifold check)"},
+	"mirror":     {Run: runMirror, Summary: "copy the archive into a directory, a .tar/.tar.zst snapshot or an S3-compatible bucket"},
+	"scrape":     {Run: runScrape, Summary: "download new files from the remote site and update the manifest and catalog"},
+	"search":     {Run: runSearch, Summary: "full-text search the archived PDFs (e.g. search \"bind button\")"},
+	"site":       {Run: runSite, Summary: "render a static HTML browser for the archive"},
+	"verify":     {Run: runVerify, Summary: "rehash every archived file against the manifest and report modified, missing and untracked files"},
+}
+
+// runCommand dispatches the arguments to a subcommand and returns the exit code.
+// Arguments that start with a flag go to the default command.
+// A leading "-site name" selects the site adapter and its output root for any command.
+func runCommand(args []string) int {
+	siteName, args := cutSiteOption(args)
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage()
+		return 0
+	}
+	selected, found := commands[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage()
+		return 2
+	}
+	if !selectSite(siteName) {
+		return 2
+	}
+	return selected.Run(args)
+}
+
+// printUsage lists the available subcommands on stderr.
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [-site name] [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].Summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nSites:\n")
+	for _, name := range slices.Sorted(maps.Keys(siteAdapters)) {
+		adapter := siteAdapters[name]
+		root := siteOutputRoot(adapter)
+		if root == "." {
+			root = "the repository root"
+		}
+		fmt.Fprintf(os.Stderr, "  %-10s %s, archived in %s\n", name, adapter.title(), root)
+	}
+	fmt.Fprintf(os.Stderr, "\nWithout a command, %q runs. Without -site, %q is archived. Use \"<command> -h\" for its flags.\n", defaultCommand, defaultSiteName)
+}
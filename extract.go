@@ -0,0 +1,367 @@
+package main // Define the main package
+
+import (
+	"archive/zip"   // Provides ZIP reading
+	"errors"        // Provides error values
+	"fmt"           // Provides formatted errors
+	"hash/crc32"    // Provides the CRC-32 of extracted files
+	"io"            // Provides basic interfaces to I/O primitives
+	"log"           // Provides logging functions
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path"          // Provides functions for manipulating slash-separated paths
+	"path/filepath" // Provides filepath manipulation functions
+	"regexp"        // Provides name normalisation
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides member modification times
+
+	"github.com/nwaples/rardecode/v2" // Pure-Go RAR 1.5-5.0 decoder
+)
+
+// Limits that stop a hostile or broken archive (zip bomb) from filling the disk.
+const (
+	extractMaxMembers   = 10000      // Files per archive
+	extractMaxFileSize  = 1 << 30    // Bytes per extracted file
+	extractMaxTotalSize = 4 << 30    // Bytes per archive
+	extractMaxRatio     = 200        // Uncompressed bytes per compressed byte
+	extractRatioFloor   = 1 << 20    // Files up to this size are never rejected for their ratio
+	extractTempSuffix   = ".partial" // Suffix of the directory an archive is unpacked into before it replaces the old tree
+)
+
+// archiveMember is one file inside a ZIP or RAR archive as listed in the manifest.
+type archiveMember struct {
+	Path           string    `json:"path"`                // Path inside the archive as stored
+	Size           int64     `json:"size"`                // Uncompressed size in bytes
+	CompressedSize int64     `json:"compressed_size"`     // Stored size in bytes
+	CRC32          string    `json:"crc32,omitempty"`     // Hex CRC-32 from the archive directory
+	Modified       time.Time `json:"modified"`            // Modification time stored in the archive
+	Type           string    `json:"type,omitempty"`      // Asset type name, or the lowercase extension of other files
+	Encrypted      bool      `json:"encrypted,omitempty"` // Password-protected; cannot be extracted
+	Extracted      string    `json:"extracted,omitempty"` // Slash path the file was unpacked to
+}
+
+// archiveWalker calls visit for every file of an archive (directories are skipped).
+// open returns the member's contents and is only valid during the call.
+type archiveWalker func(filePath string, visit func(member *archiveMember, open func() (io.ReadCloser, error)) error) error
+
+// archiveWalkers maps the asset types that are archives to their walkers.
+var archiveWalkers = map[string]archiveWalker{
+	"zip": walkZIP,
+	"rar": walkRAR,
+}
+
+// walkZIP walks the central directory of a ZIP file.
+func walkZIP(filePath string, visit func(member *archiveMember, open func() (io.ReadCloser, error)) error) error {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.Mode()&os.ModeSymlink != 0 {
+			continue // Links are never followed or created
+		}
+		member := &archiveMember{
+			Path:           strings.ToValidUTF8(file.Name, "�"), // Legacy code pages are not decoded
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+			CRC32:          fmt.Sprintf("%08x", file.CRC32),
+			Modified:       file.Modified.UTC(),
+			Type:           memberType(file.Name),
+			Encrypted:      file.Flags&0x1 != 0,
+		}
+		if err := visit(member, file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkRAR reads the file headers of a RAR4 or RAR5 archive in order. Contents are checked against the
+// archive's checksums while they are read, and dictionaries larger than the largest extractable file are refused.
+func walkRAR(filePath string, visit func(member *archiveMember, open func() (io.ReadCloser, error)) error) error {
+	reader, err := rardecode.OpenReader(filePath, rardecode.MaxDictionarySize(extractMaxFileSize))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.IsDir || header.Mode()&os.ModeSymlink != 0 {
+			continue // Links are never followed or created
+		}
+		member := &archiveMember{
+			Path:           strings.ToValidUTF8(header.Name, "�"),
+			Size:           header.UnPackedSize,
+			CompressedSize: header.PackedSize,
+			Modified:       header.ModificationTime.UTC(),
+			Type:           memberType(header.Name),
+			Encrypted:      header.Encrypted,
+		}
+		if header.UnKnownSize {
+			member.Size = 0 // Learnt while extracting
+		}
+		if err := visit(member, func() (io.ReadCloser, error) { return io.NopCloser(reader), nil }); err != nil {
+			return err
+		}
+	}
+}
+
+// memberType names the asset type of a member, or its lowercase extension when it is not an asset.
+func memberType(name string) string {
+	extension := strings.ToLower(path.Ext(strings.ReplaceAll(name, `\`, "/")))
+	if asset := assetTypeByExtension(extension); asset != nil {
+		return asset.Name
+	}
+	return strings.TrimPrefix(extension, ".")
+}
+
+// listArchive returns the files of an archive for the manifest.
+func listArchive(filePath, assetName string) ([]archiveMember, error) {
+	walk, found := archiveWalkers[assetName]
+	if !found {
+		return nil, fmt.Errorf("%s files cannot be listed", assetName)
+	}
+	var members []archiveMember
+	err := walk(filePath, func(member *archiveMember, open func() (io.ReadCloser, error)) error {
+		if len(members) == extractMaxMembers {
+			return fmt.Errorf("more than %d files", extractMaxMembers)
+		}
+		members = append(members, *member)
+		return nil
+	})
+	return members, err
+}
+
+// memberNameRegex matches the runs of characters that normalised member names replace with "_".
+var memberNameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeMemberPath turns a stored member name into a safe relative path with the same lowercase,
+// underscore-separated names as downloaded files. Absolute paths and ".." components are rejected (zip slip).
+func normalizeMemberPath(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("%q is an absolute path", name)
+	}
+	var parts []string
+	components := strings.Split(name, "/")
+	for index, component := range components {
+		if component == ".." {
+			return "", fmt.Errorf("%q leaves the extraction directory", name)
+		}
+		if component == "" || component == "." {
+			continue
+		}
+		extension := ""
+		if index == len(components)-1 {
+			extension = strings.ToLower(path.Ext(component))
+			if memberNameRegex.MatchString(strings.TrimPrefix(extension, ".")) {
+				extension = "" // Not a real extension
+			}
+			component = strings.TrimSuffix(component, path.Ext(component))
+		}
+		normalized := strings.Trim(memberNameRegex.ReplaceAllString(strings.ToLower(component), "_"), "_")
+		if normalized == "" {
+			normalized = "file"
+		}
+		parts = append(parts, normalized+extension)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("%q has no file name", name)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// extractionDir returns the directory an archive is unpacked into: its name without the extension, next to it.
+func extractionDir(archivePath string) string {
+	return strings.TrimSuffix(archivePath, path.Ext(archivePath))
+}
+
+// extractArchives unpacks every valid archive of the manifest into a directory named after it and records
+// the extracted assets, so they are validated and catalogued like downloaded files.
+// Archives whose contents have not changed since they were unpacked are left alone.
+func extractArchives(archive *archiveManifest) bool {
+	var archivePaths []string
+	for key, entry := range archive.Entries {
+		// Archives found inside archives are listed but not unpacked
+		if archiveWalkers[entry.AssetType] != nil && entry.Parent == "" && entry.Validation != nil && entry.Validation.Valid && fileExists(filepath.FromSlash(key)) {
+			archivePaths = append(archivePaths, key)
+		}
+	}
+	slices.Sort(archivePaths)
+
+	allExtracted := true
+	for _, archivePath := range archivePaths {
+		entry := archive.Entries[archivePath]
+		if !membersExtracted(entry.Contents) {
+			members, err := extractArchive(archivePath, entry.AssetType)
+			if err != nil {
+				log.Printf("Failed to extract %s: %v", archivePath, err)
+				allExtracted = false
+				continue
+			}
+			entry.Contents = members
+			log.Printf("Extracted %d files from %s into %s/", len(members), archivePath, extractionDir(archivePath))
+		}
+		// Record the extracted assets under the archive's source and link contexts
+		recorded := make(map[string]bool)
+		for _, member := range entry.Contents {
+			if member.Extracted == "" || assetTypeByExtension(path.Ext(member.Extracted)) == nil {
+				continue
+			}
+			child := archive.record(filepath.FromSlash(member.Extracted), entry.URL, entry.Contexts)
+			child.Parent = archivePath
+			recorded[child.Path] = true
+		}
+		// Forget the assets of earlier contents that the new tree no longer has
+		for key, child := range archive.Entries {
+			if child.Parent == archivePath && !recorded[key] {
+				delete(archive.Entries, key)
+			}
+		}
+	}
+	return allExtracted
+}
+
+// membersExtracted reports whether every extractable member has been unpacked and is still on disk.
+func membersExtracted(members []archiveMember) bool {
+	if members == nil {
+		return false
+	}
+	for _, member := range members {
+		if member.Encrypted {
+			continue
+		}
+		if member.Extracted == "" || !fileExists(filepath.FromSlash(member.Extracted)) {
+			return false
+		}
+	}
+	return true
+}
+
+// extractArchive unpacks an archive into a temporary directory and swaps it in for the previous tree,
+// so a failed extraction never leaves a partial tree behind. It returns the listing with the extracted paths.
+func extractArchive(archivePath, assetName string) ([]archiveMember, error) {
+	walk, found := archiveWalkers[assetName]
+	if !found {
+		return nil, fmt.Errorf("%s files cannot be extracted", assetName)
+	}
+	targetDir := extractionDir(archivePath)
+	temporaryDir := filepath.FromSlash(targetDir) + extractTempSuffix
+	if err := os.RemoveAll(temporaryDir); err != nil {
+		return nil, err
+	}
+
+	var members []archiveMember
+	var total int64
+	used := make(map[string]bool)
+	err := walk(filepath.FromSlash(archivePath), func(member *archiveMember, open func() (io.ReadCloser, error)) error {
+		if len(members) == extractMaxMembers {
+			return fmt.Errorf("more than %d files", extractMaxMembers)
+		}
+		if member.Encrypted {
+			log.Printf("Skipping encrypted %s in %s", member.Path, archivePath)
+			members = append(members, *member)
+			return nil
+		}
+		relative, err := normalizeMemberPath(member.Path)
+		if err != nil {
+			return err
+		}
+		// Archives often wrap everything in a folder named like the archive itself
+		if trimmed, found := strings.CutPrefix(relative, path.Base(targetDir)+"/"); found {
+			relative = trimmed
+		}
+		relative = uniqueMemberPath(relative, used)
+
+		// Check the declared sizes first, then enforce them while writing in case they lie
+		limit := min(extractMaxFileSize, extractMaxTotalSize-total, max(extractRatioFloor, extractMaxRatio*member.CompressedSize))
+		if member.Size > limit {
+			return fmt.Errorf("%s: %d bytes exceeds the extraction limits", member.Path, member.Size)
+		}
+		targetFile := filepath.Join(temporaryDir, filepath.FromSlash(relative))
+		if !strings.HasPrefix(targetFile, temporaryDir+string(filepath.Separator)) {
+			return fmt.Errorf("%q leaves the extraction directory", member.Path)
+		}
+		written, err := extractMember(open, targetFile, member, limit)
+		if err != nil {
+			return fmt.Errorf("%s: %v", member.Path, err)
+		}
+		total += written
+		member.Extracted = targetDir + "/" + relative
+		members = append(members, *member)
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(temporaryDir)
+		return nil, err
+	}
+	if len(members) == 0 {
+		// Nothing to unpack; make sure an empty directory still exists so the archive counts as extracted
+		if err := os.MkdirAll(temporaryDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.RemoveAll(filepath.FromSlash(targetDir)); err != nil {
+		os.RemoveAll(temporaryDir)
+		return nil, err
+	}
+	if err := os.Rename(temporaryDir, filepath.FromSlash(targetDir)); err != nil {
+		os.RemoveAll(temporaryDir)
+		return nil, err
+	}
+	return members, nil
+}
+
+// uniqueMemberPath appends _2, _3 ... before the extension when two members normalise to the same path.
+func uniqueMemberPath(relative string, used map[string]bool) string {
+	candidate := relative
+	extension := path.Ext(relative)
+	for number := 2; used[candidate]; number++ {
+		candidate = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(relative, extension), number, extension)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// extractMember writes one member to targetFile, refusing to write more than limit bytes,
+// and checks the CRC-32 when the archive lists one.
+func extractMember(open func() (io.ReadCloser, error), targetFile string, member *archiveMember, limit int64) (int64, error) {
+	reader, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	if err := os.MkdirAll(filepath.Dir(targetFile), 0o755); err != nil {
+		return 0, err
+	}
+	out, err := os.Create(targetFile)
+	if err != nil {
+		return 0, err
+	}
+	hasher := crc32.NewIEEE()
+	written, err := io.Copy(io.MultiWriter(out, hasher), io.LimitReader(reader, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	if written > limit {
+		return written, errors.New("decompresses to more than the extraction limits allow (possible zip bomb)")
+	}
+	if member.CRC32 != "" && fmt.Sprintf("%08x", hasher.Sum32()) != member.CRC32 {
+		return written, errors.New("CRC-32 mismatch")
+	}
+	member.Size = written
+	if !member.Modified.IsZero() {
+		os.Chtimes(targetFile, member.Modified, member.Modified)
+	}
+	return written, nil
+}
@@ -1,5 +1,9 @@
-module main
+module caddx-archiver
 
 go 1.24.5
 
-require golang.org/x/net v0.44.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/nwaples/rardecode/v2 v2.2.0
+	golang.org/x/net v0.44.0
+)
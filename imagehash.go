@@ -0,0 +1,369 @@
+package main // Define the main package
+
+import (
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output
+	"image"         // Provides image decoding
+	"io"            // Provides seeking back to the start of the file
+	"log"           // Provides logging functions
+	"math"          // Provides the DCT
+	"math/bits"     // Provides the Hamming distance
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice sorting helpers
+	"strconv"       // Provides hex parsing of stored hashes
+	"strings"       // Provides string comparison
+)
+
+// Two images are near-duplicates when their dHash and pHash distances add up to at most this many bits (of 128).
+// Re-encoded and re-cropped copies of one picture score up to about 8; renders of sibling products that differ
+// only in a small part score 10 and more.
+const duplicateMaxHashDistance = 8
+
+// imageContentThreshold is the grey level below which a pixel counts as content rather than white background.
+const imageContentThreshold = 0.96
+
+// maxDecodedImagePixels caps the size of images that are decoded for hashing and thumbnails. Decoding needs about 20 bytes
+// per pixel, and a few bytes of PNG or JPEG header can claim billions of pixels.
+const maxDecodedImagePixels = 50_000_000
+
+// imageFingerprint is the size and perceptual hashes of an image, stored in the manifest.
+type imageFingerprint struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	DHash  string `json:"dhash"` // 64-bit difference hash (horizontal gradients of a 9×8 thumbnail), hex
+	PHash  string `json:"phash"` // 64-bit DCT hash (low frequencies of a 32×32 thumbnail), hex
+}
+
+// fingerprintImageFile decodes an image and computes its perceptual hashes.
+// Transparent pixels are composited onto white, so cut-outs match their white-background versions.
+func fingerprintImageFile(filePath string) (*imageFingerprint, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	// Check the declared size before decoding allocates the pixels
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > maxDecodedImagePixels {
+		return nil, fmt.Errorf("image is too large to fingerprint (%d×%d, limit %d pixels)", config.Width, config.Height, maxDecodedImagePixels)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	decoded, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	bounds := decoded.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("image has no pixels")
+	}
+
+	// Grey values composited onto white, from 0 (black) to 1 (white)
+	width, height := bounds.Dx(), bounds.Dy()
+	grey := make([]float32, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			red, green, blue, alpha := decoded.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			background := float64(0xffff - alpha)
+			grey[y*width+x] = float32((0.299*(float64(red)+background) + 0.587*(float64(green)+background) + 0.114*(float64(blue)+background)) / 0xffff)
+		}
+	}
+
+	// Product shots are mostly white background, which would make unrelated pictures look alike;
+	// hash only the bounding box of the visible content
+	left, top, right, bottom := width, height, 0, 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if grey[y*width+x] < imageContentThreshold {
+				left, right = min(left, x), max(right, x+1)
+				top, bottom = min(top, y), max(bottom, y+1)
+			}
+		}
+	}
+	if left >= right || top >= bottom {
+		left, top, right, bottom = 0, 0, width, height // Blank image
+	}
+	contentWidth, contentHeight := right-left, bottom-top
+
+	content := make([]float64, contentWidth*contentHeight)
+	for y := 0; y < contentHeight; y++ {
+		for x := 0; x < contentWidth; x++ {
+			content[y*contentWidth+x] = float64(grey[(top+y)*width+left+x])
+		}
+	}
+	const side = 32
+	thumbnail := resampleArea(content, contentWidth, contentHeight, side, side)
+
+	return &imageFingerprint{
+		Width:  width,
+		Height: height,
+		DHash:  fmt.Sprintf("%016x", differenceHash(thumbnail, side)),
+		PHash:  fmt.Sprintf("%016x", dctHash(thumbnail, side)),
+	}, nil
+}
+
+// resampleArea scales a grid of values with a box filter: every output cell is the area-weighted mean of the
+// input cells it covers, which neither aliases when shrinking nor leaves gaps when enlarging.
+func resampleArea(values []float64, width, height, outWidth, outHeight int) []float64 {
+	// weights returns, for each output index, the input indices it covers and how much of each
+	type span struct {
+		first   int
+		weights []float64
+	}
+	spans := func(inSize, outSize int) []span {
+		result := make([]span, outSize)
+		scale := float64(inSize) / float64(outSize)
+		for index := range result {
+			start, end := float64(index)*scale, float64(index+1)*scale
+			first := int(start)
+			result[index].first = first
+			for cell := first; float64(cell) < end && cell < inSize; cell++ {
+				overlap := math.Min(end, float64(cell+1)) - math.Max(start, float64(cell))
+				result[index].weights = append(result[index].weights, overlap/scale)
+			}
+		}
+		return result
+	}
+	columns, rows := spans(width, outWidth), spans(height, outHeight)
+
+	// Horizontal pass, then vertical pass
+	horizontal := make([]float64, height*outWidth)
+	for y := 0; y < height; y++ {
+		for x, column := range columns {
+			total := 0.0
+			for offset, weight := range column.weights {
+				total += values[y*width+column.first+offset] * weight
+			}
+			horizontal[y*outWidth+x] = total
+		}
+	}
+	result := make([]float64, outHeight*outWidth)
+	for y, row := range rows {
+		for x := 0; x < outWidth; x++ {
+			total := 0.0
+			for offset, weight := range row.weights {
+				total += horizontal[(row.first+offset)*outWidth+x] * weight
+			}
+			result[y*outWidth+x] = total
+		}
+	}
+	return result
+}
+
+// differenceHash shrinks the thumbnail to 9×8 and sets a bit wherever a pixel is brighter than its left neighbour.
+func differenceHash(thumbnail []float64, side int) uint64 {
+	const width, height = 9, 8
+	small := resampleArea(thumbnail, side, side, width, height)
+	var hash uint64
+	for row := 0; row < height; row++ {
+		for column := 0; column < width-1; column++ {
+			hash <<= 1
+			if small[row*width+column+1] > small[row*width+column] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// dctHash takes the 2-D DCT of the thumbnail and sets a bit for each of the 8×8 lowest frequencies above their median.
+func dctHash(thumbnail []float64, side int) uint64 {
+	const size = 8
+	// Separable DCT-II, only the low-frequency rows and columns are needed
+	rows := make([]float64, side*size) // rows[y*size+u]: DCT of row y at frequency u
+	for y := 0; y < side; y++ {
+		for u := 0; u < size; u++ {
+			total := 0.0
+			for x := 0; x < side; x++ {
+				total += thumbnail[y*side+x] * math.Cos(math.Pi*float64(u)*(2*float64(x)+1)/float64(2*side))
+			}
+			rows[y*size+u] = total
+		}
+	}
+	var coefficients [size * size]float64
+	for v := 0; v < size; v++ {
+		for u := 0; u < size; u++ {
+			total := 0.0
+			for y := 0; y < side; y++ {
+				total += rows[y*size+u] * math.Cos(math.Pi*float64(v)*(2*float64(y)+1)/float64(2*side))
+			}
+			coefficients[v*size+u] = total
+		}
+	}
+	sorted := slices.Clone(coefficients[:])
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	var hash uint64
+	for _, coefficient := range coefficients {
+		hash <<= 1
+		if coefficient > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// hashDistance returns the number of differing bits between two hex hashes, or 64 if either is unreadable.
+func hashDistance(left, right string) int {
+	leftValue, leftErr := strconv.ParseUint(left, 16, 64)
+	rightValue, rightErr := strconv.ParseUint(right, 16, 64)
+	if leftErr != nil || rightErr != nil {
+		return 64
+	}
+	return bits.OnesCount64(leftValue ^ rightValue)
+}
+
+// nearDuplicates reports whether two fingerprints are close enough to be the same picture.
+func nearDuplicates(left, right *imageFingerprint) bool {
+	return hashDistance(left.DHash, right.DHash)+hashDistance(left.PHash, right.PHash) <= duplicateMaxHashDistance
+}
+
+// imageDuplicateClusters groups the fingerprinted images of the manifest that are near-duplicates of each other.
+// Each cluster is sorted best first: the highest resolution, then the largest file, then the path.
+func imageDuplicateClusters(archive *archiveManifest) [][]*manifestEntry {
+	var images []*manifestEntry
+	for _, entry := range archive.Entries {
+		if entry.ImageHash != nil && entry.AliasOf == "" {
+			images = append(images, entry)
+		}
+	}
+	slices.SortFunc(images, func(left, right *manifestEntry) int { return strings.Compare(left.Path, right.Path) })
+
+	// Union-find over all near-duplicate pairs
+	parent := make([]int, len(images))
+	for index := range parent {
+		parent[index] = index
+	}
+	var find func(int) int
+	find = func(index int) int {
+		if parent[index] != index {
+			parent[index] = find(parent[index])
+		}
+		return parent[index]
+	}
+	for left := range images {
+		for right := left + 1; right < len(images); right++ {
+			if nearDuplicates(images[left].ImageHash, images[right].ImageHash) {
+				parent[find(right)] = find(left)
+			}
+		}
+	}
+
+	groups := make(map[int][]*manifestEntry)
+	for index, entry := range images {
+		groups[find(index)] = append(groups[find(index)], entry)
+	}
+	var clusters [][]*manifestEntry
+	for _, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		slices.SortFunc(members, func(left, right *manifestEntry) int {
+			leftPixels := left.ImageHash.Width * left.ImageHash.Height
+			rightPixels := right.ImageHash.Width * right.ImageHash.Height
+			if leftPixels != rightPixels {
+				return rightPixels - leftPixels
+			}
+			if left.Size != right.Size {
+				return int(right.Size - left.Size)
+			}
+			return strings.Compare(left.Path, right.Path)
+		})
+		clusters = append(clusters, members)
+	}
+	slices.SortFunc(clusters, func(left, right []*manifestEntry) int { return strings.Compare(left[0].Path, right[0].Path) })
+	return clusters
+}
+
+// applyImageAliases keeps the best image of every cluster and removes the others that are near-duplicates
+// of it from disk, recording them as aliases of the kept file so they are neither catalogued nor downloaded again.
+// Files extracted from archives are left alone, since extraction would only recreate them.
+func applyImageAliases(archive *archiveManifest, clusters [][]*manifestEntry) int {
+	aliased := 0
+	for _, cluster := range clusters {
+		keeper := cluster[0]
+		for _, duplicate := range cluster[1:] {
+			if duplicate.Parent != "" {
+				continue
+			}
+			// Clusters chain pairs (A≈B, B≈C), so only images close to the kept one itself are removed
+			if !nearDuplicates(keeper.ImageHash, duplicate.ImageHash) {
+				continue
+			}
+			if err := os.Remove(filepath.FromSlash(duplicate.Path)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove duplicate %s: %v", duplicate.Path, err)
+				continue
+			}
+			duplicate.AliasOf = keeper.Path
+			duplicate.Preview = ""
+			aliased++
+			log.Printf("Replaced %s (%d×%d) with an alias of %s (%d×%d)", duplicate.Path,
+				duplicate.ImageHash.Width, duplicate.ImageHash.Height, keeper.Path, keeper.ImageHash.Width, keeper.ImageHash.Height)
+		}
+	}
+	return aliased
+}
+
+// runDuplicates implements the duplicates command: print the clusters of near-duplicate images
+// and, with -apply, keep the highest-resolution image of each and record the others as aliases.
+func runDuplicates(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "remove the smaller copies and record them as aliases of the kept image")
+	flags.Parse(args)
+
+	archive := loadManifest(manifestPath)
+	// Fingerprint images recorded before the manifest kept hashes
+	fingerprinted := 0
+	for _, entry := range archive.Entries {
+		if (entry.AssetType != "jpg" && entry.AssetType != "png") || entry.ImageHash != nil || entry.AliasOf != "" {
+			continue
+		}
+		if entry.Validation != nil && !entry.Validation.Valid || !fileExists(filepath.FromSlash(entry.Path)) {
+			continue
+		}
+		fingerprint, err := fingerprintImageFile(filepath.FromSlash(entry.Path))
+		if err != nil {
+			log.Printf("Failed to fingerprint %s: %v", entry.Path, err)
+			continue
+		}
+		entry.ImageHash = fingerprint
+		fingerprinted++
+	}
+
+	clusters := imageDuplicateClusters(archive)
+	for index, cluster := range clusters {
+		if index > 0 {
+			fmt.Println()
+		}
+		keeper := cluster[0]
+		fmt.Printf("keep  %s (%d×%d, %d bytes)\n", keeper.Path, keeper.ImageHash.Width, keeper.ImageHash.Height, keeper.Size)
+		for _, duplicate := range cluster[1:] {
+			action := "alias"
+			if !nearDuplicates(keeper.ImageHash, duplicate.ImageHash) {
+				action = "keep " // Only similar through another member of the cluster
+			}
+			fmt.Printf("%s %s (%d×%d, %d bytes, dhash %d, phash %d)\n", action, duplicate.Path,
+				duplicate.ImageHash.Width, duplicate.ImageHash.Height, duplicate.Size,
+				hashDistance(keeper.ImageHash.DHash, duplicate.ImageHash.DHash),
+				hashDistance(keeper.ImageHash.PHash, duplicate.ImageHash.PHash))
+		}
+	}
+	if len(clusters) == 0 {
+		fmt.Println("No near-duplicate images found")
+	}
+
+	aliased := 0
+	if *apply {
+		aliased = applyImageAliases(archive, clusters)
+	}
+	if (fingerprinted > 0 || aliased > 0) && !saveManifest(manifestPath, archive) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package main // Define the main package
+
+import (
+	"bytes"           // Provides the PNG buffer
+	"encoding/binary" // Provides the big-endian IHDR fields
+	"hash/crc32"      // Provides the PNG chunk checksum
+	"image"           // Provides the test image
+	"image/color"     // Provides pixel colours
+	"image/png"       // Encodes the test image
+	"os"              // Provides file writing
+	"path/filepath"   // Provides filepath manipulation functions
+	"testing"         // Provides the test runner
+)
+
+// testPNG encodes a white square with a dark block in the middle.
+func testPNG(t *testing.T, size int) []byte {
+	picture := image.NewGray(image.Rect(0, 0, size, size))
+	for y := range size {
+		for x := range size {
+			picture.SetGray(x, y, color.Gray{Y: 255})
+			if x >= size/4 && x < 3*size/4 && y >= size/4 && y < 3*size/4 {
+				picture.SetGray(x, y, color.Gray{Y: 40})
+			}
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, picture); err != nil {
+		t.Fatal(err)
+	}
+	return encoded.Bytes()
+}
+
+// withPNGSize rewrites the width and height in the IHDR chunk and fixes its checksum, so the file
+// claims a size its pixel data does not have.
+func withPNGSize(data []byte, width, height uint32) []byte {
+	patched := bytes.Clone(data)
+	header := patched[8+8 : 8+8+13] // After the signature, chunk length and "IHDR"
+	binary.BigEndian.PutUint32(header[0:], width)
+	binary.BigEndian.PutUint32(header[4:], height)
+	binary.BigEndian.PutUint32(patched[8+8+13:], crc32.ChecksumIEEE(patched[8+4:8+8+13]))
+	return patched
+}
+
+// TestFingerprintImageFile checks that normal images are hashed and that images claiming more pixels than
+// the limit are refused before they are decoded.
+func TestFingerprintImageFile(t *testing.T) {
+	small := testPNG(t, 64)
+	tests := []struct {
+		name    string
+		data    []byte
+		success bool
+	}{
+		{"small image", small, true},
+		{"declared 30000×30000", withPNGSize(small, 30000, 30000), false},
+		{"declared 1×4000000000", withPNGSize(small, 1, 4_000_000_000), false},
+		{"not an image", []byte("<html>Not Found</html>"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "image.png")
+			if err := os.WriteFile(filePath, test.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fingerprint, err := fingerprintImageFile(filePath)
+			if !test.success {
+				if err == nil {
+					t.Fatalf("fingerprintImageFile accepted the file as %d×%d", fingerprint.Width, fingerprint.Height)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fingerprintImageFile failed: %v", err)
+			}
+			if fingerprint.Width != 64 || fingerprint.Height != 64 || len(fingerprint.DHash) != 16 || len(fingerprint.PHash) != 16 {
+				t.Errorf("fingerprint = %+v, want a 64×64 image with two 64-bit hashes", fingerprint)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package main // Define the main package
+
+import (
+	"encoding/json" // Provides JSON output
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"strings"       // Provides string manipulation functions
+)
+
+// fileInspection is what the inspect command reports for one file.
+type fileInspection struct {
+	Path       string            `json:"path"`
+	URL        string            `json:"url,omitempty"` // Source URL from the manifest
+	Size       int64             `json:"size"`
+	SHA256     string            `json:"sha256"`
+	Validation *validationResult `json:"validation"`
+	STL        *stlAnalysis      `json:"stl,omitempty"`
+	STEP       *stepMetadata     `json:"step,omitempty"`
+	Contents   []archiveMember   `json:"contents,omitempty"`
+}
+
+// runInspect implements the inspect command: validate and analyse files and print the results.
+// Without arguments it inspects every archived STL and STEP model.
+func runInspect(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the results as JSON")
+	flags.Parse(args)
+
+	archive := loadManifest(manifestPath)
+	paths := flags.Args()
+	if len(paths) == 0 {
+		for _, assetName := range []string{"stl", "stp", "step"} {
+			paths = append(paths, archiveFilesOfType(archive, assetName)...)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: inspect [-json] [file ...]")
+		return 2
+	}
+
+	exitCode := 0
+	var inspections []*fileInspection
+	for _, path := range paths {
+		inspection, err := inspectFile(archive, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			exitCode = 1
+			continue
+		}
+		if !inspection.Validation.Valid {
+			exitCode = 1
+		}
+		inspections = append(inspections, inspection)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(inspections)
+		return exitCode
+	}
+	for index, inspection := range inspections {
+		if index > 0 {
+			fmt.Println()
+		}
+		printInspection(inspection)
+	}
+	return exitCode
+}
+
+// inspectFile hashes, validates and analyses one file, taking the source URL from the manifest.
+func inspectFile(archive *archiveManifest, path string) (*fileInspection, error) {
+	size, sum, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := filepath.ToSlash(filepath.Clean(path))
+	inspection := &fileInspection{Path: key, Size: size, SHA256: sum}
+	if entry, found := archive.Entries[key]; found {
+		inspection.URL = entry.URL
+	}
+	asset := assetTypeByExtension(filepath.Ext(key))
+	inspection.Validation = validateArchiveFile(path, asset, sum)
+	if asset != nil && asset.Name == "stl" && inspection.Validation.Valid {
+		if inspection.STL, err = analyzeSTLFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if asset != nil && (asset.Name == "stp" || asset.Name == "step") && inspection.Validation.Valid {
+		if inspection.STEP, err = parseSTEPFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if asset != nil && archiveWalkers[asset.Name] != nil && inspection.Validation.Valid {
+		if inspection.Contents, err = listArchive(path, asset.Name); err != nil {
+			return nil, err
+		}
+	}
+	return inspection, nil
+}
+
+// printInspection writes a human-readable report for one file.
+func printInspection(inspection *fileInspection) {
+	fmt.Println(inspection.Path)
+	if inspection.URL != "" {
+		fmt.Printf("  Source:        %s\n", inspection.URL)
+	}
+	fmt.Printf("  Size:          %s (%d bytes)\n", formatSize(inspection.Size), inspection.Size)
+	fmt.Printf("  SHA-256:       %s\n", inspection.SHA256)
+	if inspection.Validation.Valid {
+		fmt.Printf("  Valid:         yes (%s)\n", inspection.Validation.Detail)
+	} else {
+		fmt.Printf("  Valid:         NO (%s)\n", inspection.Validation.Error)
+	}
+
+	if stl := inspection.STL; stl != nil {
+		fmt.Printf("  Format:        %s STL\n", stl.Format)
+		fmt.Printf("  Triangles:     %d\n", stl.Triangles)
+		fmt.Printf("  Dimensions:    %.2f × %.2f × %.2f mm\n", stl.Size[0], stl.Size[1], stl.Size[2])
+		fmt.Printf("  Bounding box:  (%.2f, %.2f, %.2f) to (%.2f, %.2f, %.2f) mm\n",
+			stl.Min[0], stl.Min[1], stl.Min[2], stl.Max[0], stl.Max[1], stl.Max[2])
+		fmt.Printf("  Surface area:  %.2f mm²\n", stl.SurfaceArea)
+		volumeNote := ""
+		if stl.Volume < 0 {
+			volumeNote = " (negative: the mesh is inside out)"
+		}
+		fmt.Printf("  Volume:        %.2f mm³%s\n", stl.Volume, volumeNote)
+		var problems []string
+		if stl.NonManifoldEdges > 0 {
+			problems = append(problems, fmt.Sprintf("%d non-manifold edges", stl.NonManifoldEdges))
+		}
+		if stl.FlippedNormals > 0 {
+			problems = append(problems, fmt.Sprintf("%d flipped normals", stl.FlippedNormals))
+		}
+		if stl.NormalMismatches > 0 {
+			problems = append(problems, fmt.Sprintf("%d stored normals disagree with the winding", stl.NormalMismatches))
+		}
+		if stl.Watertight && len(problems) == 0 {
+			fmt.Println("  Mesh:          watertight, consistent normals")
+		} else {
+			state := "not watertight"
+			if stl.Watertight {
+				state = "watertight"
+			}
+			fmt.Printf("  Mesh:          %s; %s\n", state, strings.Join(problems, ", "))
+		}
+	}
+	if step := inspection.STEP; step != nil {
+		if step.Protocol != "" {
+			fmt.Printf("  Schema:        %s (%s)\n", strings.Join(step.Schemas, ", "), step.Protocol)
+		} else if len(step.Schemas) > 0 {
+			fmt.Printf("  Schema:        %s\n", strings.Join(step.Schemas, ", "))
+		}
+		if step.Name != "" {
+			fmt.Printf("  Name:          %s\n", step.Name)
+		}
+		if step.Timestamp != "" {
+			fmt.Printf("  Exported:      %s\n", step.Timestamp)
+		}
+		if len(step.Authors) > 0 {
+			fmt.Printf("  Author:        %s\n", strings.Join(step.Authors, ", "))
+		}
+		if len(step.Organizations) > 0 {
+			fmt.Printf("  Organization:  %s\n", strings.Join(step.Organizations, ", "))
+		}
+		if step.OriginatingSystem != "" {
+			fmt.Printf("  System:        %s\n", step.OriginatingSystem)
+		}
+		if step.Preprocessor != "" && step.Preprocessor != step.OriginatingSystem {
+			fmt.Printf("  Translator:    %s\n", step.Preprocessor)
+		}
+		if len(step.Description) > 0 {
+			fmt.Printf("  Description:   %s\n", strings.Join(step.Description, "; "))
+		}
+		fmt.Printf("  Entities:      %d (%d types)\n", step.Entities, len(step.EntityTypes))
+		// The solid and surface bodies say most about what the file contains
+		for _, entityType := range stepBodyTypes {
+			if count := step.EntityTypes[entityType]; count > 0 {
+				fmt.Printf("    %-31s %d\n", entityType, count)
+			}
+		}
+		if len(step.Products) > 0 {
+			fmt.Printf("  Products:      %d\n", len(step.Products))
+			for _, product := range step.Products {
+				fmt.Printf("    %s\n", product)
+			}
+		}
+	}
+	if len(inspection.Contents) > 0 {
+		fmt.Printf("  Contents:      %d files\n", len(inspection.Contents))
+		for _, member := range inspection.Contents {
+			note := ""
+			if member.Encrypted {
+				note = " (encrypted)"
+			}
+			fmt.Printf("    %10s  %s%s\n", formatSize(member.Size), member.Path, note)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main // Define the main package
+
+import (
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path"          // Provides functions for manipulating slash-separated paths
+	"path/filepath" // Provides filepath manipulation functions
+	"regexp"        // Provides regex support functions.
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+	"unicode"       // Provides script tables for text detection
+
+	"golang.org/x/net/html" // Provides HTML parsing functions
+)
+
+// undeterminedLanguage is the BCP 47 code recorded when no language could be detected.
+const undeterminedLanguage = "und"
+
+// languageTaggedTypes are the asset types whose language is detected: documents and the archives that bundle them.
+var languageTaggedTypes = map[string]bool{"pdf": true, "zip": true, "rar": true}
+
+// filenameLanguageTokens maps the language suffixes used in file names (e.g. "_en_chs_v1_2") to BCP 47 codes.
+var filenameLanguageTokens = map[string]string{
+	"en": "en", "eng": "en", "english": "en",
+	"cn": "zh", "chs": "zh", "cht": "zh", "zh": "zh", "sc": "zh", "tc": "zh", "chinese": "zh",
+	"jp": "ja", "ja": "ja", "jpn": "ja", "japanese": "ja",
+	"kr": "ko", "ko": "ko", "kor": "ko", "korean": "ko",
+	"de": "de", "ger": "de", "german": "de",
+	"fr": "fr", "fre": "fr", "french": "fr",
+	"es": "es", "spa": "es", "spanish": "es",
+	"it": "it", "ita": "it", "italian": "it",
+	"ru": "ru", "rus": "ru", "russian": "ru",
+	"pt": "pt", "por": "pt", "portuguese": "pt",
+}
+
+// versionTokenRegex matches the version and date tokens that follow the language suffix (e.g. "v1", "2", "2019").
+var versionTokenRegex = regexp.MustCompile(`^v?\d+$`)
+
+// latinStopwords are frequent short words that tell the Latin-script languages apart.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "to", "of", "is", "for", "with", "please", "this", "you"},
+	"de": {"der", "die", "und", "das", "ist", "mit", "nicht", "bitte", "sie", "werden"},
+	"fr": {"le", "la", "les", "et", "est", "pour", "avec", "vous", "des", "une"},
+	"es": {"el", "los", "las", "y", "es", "para", "con", "por", "una", "del"},
+	"it": {"il", "gli", "di", "per", "con", "non", "della", "una", "sono", "che"},
+	"pt": {"os", "em", "para", "com", "não", "uma", "são", "da", "você", "ao"},
+}
+
+// minimumScriptShare is the share of letters a script needs before its language is reported,
+// so a few Chinese characters in an English manual (or model names in a Chinese one) do not count.
+const minimumScriptShare = 0.1
+
+// detectEntryLanguages returns the sorted languages of an archived document, from the language suffix of its
+// file name and, for PDFs, from the extracted text. Archives also use the names of the files inside.
+func detectEntryLanguages(entry *manifestEntry) []string {
+	languages := filenameLanguages(path.Base(entry.Path))
+	for _, member := range entry.Contents {
+		languages = append(languages, filenameLanguages(path.Base(member.Path))...)
+	}
+	if entry.AssetType == "pdf" {
+		languages = append(languages, pdfLanguages(filepath.FromSlash(entry.Path))...)
+	}
+	slices.Sort(languages)
+	languages = slices.Compact(languages)
+	if len(languages) == 0 {
+		return []string{undeterminedLanguage}
+	}
+	return languages
+}
+
+// filenameLanguages reads the language suffix of a file name: the run of language tokens right before the
+// version number or extension, as in "gofilm_20_manual_en_chs_v1_2.pdf" or "goggles_manual_en_v1_0.pdf".
+// Language-like words elsewhere in the name ("how it works") are ignored.
+func filenameLanguages(fileName string) []string {
+	tokens := productTokens(strings.TrimSuffix(fileName, path.Ext(fileName)))
+	end := len(tokens)
+	for end > 0 && versionTokenRegex.MatchString(tokens[end-1]) {
+		end--
+	}
+	var languages []string
+	for index := end - 1; index > 0; index-- { // The first token is the product, never a language
+		language, found := filenameLanguageTokens[tokens[index]]
+		if !found {
+			break
+		}
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// pdfLanguages extracts the text of a PDF and detects its languages. Unreadable PDFs yield none.
+func pdfLanguages(filePath string) []string {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Printf("Failed to read %s: %v", filePath, err)
+		return nil
+	}
+	document, err := openPDF(data)
+	if err != nil {
+		return nil
+	}
+	pages, _ := document.extractPageText()
+	return textLanguages(strings.Join(pages, "\n"))
+}
+
+// textLanguages detects the languages of a text by script: Han, kana, Hangul and Cyrillic letters name their
+// language directly, and Latin text is told apart by its most frequent stopwords. Bilingual manuals report both.
+func textLanguages(text string) []string {
+	var han, kana, hangul, cyrillic, latin, total int
+	for _, character := range text {
+		switch {
+		case !unicode.IsLetter(character):
+			continue
+		case unicode.Is(unicode.Han, character):
+			han++
+		case unicode.In(character, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, character):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, character):
+			cyrillic++
+		case unicode.Is(unicode.Latin, character):
+			latin++
+		}
+		total++
+	}
+	if total == 0 {
+		return nil
+	}
+	share := func(count int) bool { return float64(count)/float64(total) >= minimumScriptShare }
+	var languages []string
+	switch {
+	case share(kana):
+		languages = append(languages, "ja") // Japanese mixes kana with Han characters
+	case share(han):
+		languages = append(languages, "zh")
+	}
+	if share(hangul) {
+		languages = append(languages, "ko")
+	}
+	if share(cyrillic) {
+		languages = append(languages, "ru")
+	}
+	if share(latin) {
+		if language := latinLanguage(text); language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// latinLanguage returns the Latin-script language whose stopwords occur most often in the text, or "" if
+// too few stopwords occur to tell (e.g. a parts list).
+func latinLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(character rune) bool { return !unicode.IsLetter(character) }) {
+		counts[word]++
+	}
+	best, bestScore := "", 0
+	for _, language := range slices.Sorted(maps.Keys(latinStopwords)) {
+		score := 0
+		for _, stopword := range latinStopwords[language] {
+			score += counts[stopword]
+		}
+		if score > bestScore {
+			best, bestScore = language, score
+		}
+	}
+	if bestScore < 5 {
+		return ""
+	}
+	return best
+}
+
+// localeVariantPages returns the seed pages in each of the locales, so manuals that only the translated
+// pages link are discovered too. Without locales the site's configured ones are used, and without those
+// the translations every seed page announces in its hreflang links.
+func localeVariantPages(seeds, locales []string) []string {
+	if len(locales) == 0 {
+		locales = currentSite.locales()
+	}
+	var pages []string
+	if len(locales) == 0 {
+		for _, seed := range seeds {
+			pages = append(pages, alternateLanguagePages(getDataFromURL(seed), seed)...)
+		}
+		if len(pages) == 0 {
+			log.Printf("The seed pages of site %s link no translations; pass the locales with -locales", currentSite.name())
+		}
+		return pages
+	}
+	for _, locale := range locales {
+		for _, seed := range seeds {
+			if localized := currentSite.localeURL(seed, locale); localized != "" {
+				pages = append(pages, localized)
+			}
+		}
+	}
+	return pages
+}
+
+// alternateLanguagePages returns the translated versions of a page that its <link rel="alternate" hreflang>
+// tags list, as Shopify themes emit for every published locale. The x-default entry and the page itself are skipped.
+func alternateLanguagePages(htmlContent, pageURL string) []string {
+	document, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+	baseURL := documentBaseURL(document, pageURL)
+	var pages []string
+	var traverse func(node *html.Node)
+	traverse = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "link" && hasToken(attributeValue(node, "rel"), "alternate") {
+			language := strings.ToLower(attributeValue(node, "hreflang"))
+			link := resolveLinkURL(baseURL, attributeValue(node, "href"))
+			if language != "" && language != "x-default" && link != "" && link != pageURL && !slices.Contains(pages, link) {
+				pages = append(pages, link)
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			traverse(child)
+		}
+	}
+	traverse(document)
+	return pages
+}
@@ -0,0 +1,71 @@
+package main // Define the main package
+
+import (
+	"slices"  // Provides slice comparison
+	"testing" // Provides the test runner
+)
+
+// TestFilenameLanguages checks that only the language suffix before the version number is read.
+func TestFilenameLanguages(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected []string
+	}{
+		{"gofilm_20_manual_en_chs_v1_2.pdf", []string{"zh", "en"}},
+		{"avatar_kit_quick_start_guide_en_cn_v1_1.pdf", []string{"zh", "en"}},
+		{"goggles_x_extension_module_v1_1_en_chs.pdf", []string{"zh", "en"}},
+		{"goggles_manual_en_v1_0.pdf", []string{"en"}},
+		{"ratel_pro_manual_v1_2.pdf", nil},
+		{"orca_v1_1_ai2019_11_05.pdf", nil},
+		{"how_it_works_manual.pdf", nil}, // "it" is not part of a trailing suffix
+		{"en.pdf", nil},                  // The first token is the product, never a language
+		{"loris_manual_English.pdf", []string{"en"}},
+	}
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			if actual := filenameLanguages(test.fileName); !slices.Equal(actual, test.expected) {
+				t.Errorf("filenameLanguages(%q) = %q, want %q", test.fileName, actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestTextLanguages checks the script and stopword detection on short bilingual and monolingual texts.
+func TestTextLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"english", "Please read this manual before you connect the camera to the power supply and the goggles.", []string{"en"}},
+		{"chinese", "图像传感器 水平分辨率 最小光照度 镜头 帧频 功耗 电压", []string{"zh"}},
+		{"bilingual", "请在使用前阅读本说明书 Please read this manual before you use the product and keep it for the future.", []string{"zh", "en"}},
+		{"german", "Bitte lesen Sie die Anleitung, bevor Sie das Gerät mit der Stromversorgung verbinden und die Kamera nicht öffnen.", []string{"de"}},
+		{"parts list", "FOV 160 WDR NTSC PAL", nil},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := textLanguages(test.text); !slices.Equal(actual, test.expected) {
+				t.Errorf("textLanguages(%q) = %q, want %q", test.text, actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestAlternateLanguagePages checks that the translations are read from the hreflang links of a page.
+func TestAlternateLanguagePages(t *testing.T) {
+	page := `<html><head>
+		<link rel="canonical" href="https://caddxfpv.com/pages/download-center">
+		<link rel="alternate" hreflang="x-default" href="https://caddxfpv.com/pages/download-center">
+		<link rel="alternate" hreflang="en" href="https://caddxfpv.com/pages/download-center">
+		<link rel="alternate" hreflang="zh-CN" href="/zh-cn/pages/download-center">
+		<link rel="alternate" hreflang="ja" href="https://caddxfpv.com/ja/pages/download-center">
+		<link rel="alternate" hreflang="ja" href="https://caddxfpv.com/ja/pages/download-center">
+		<link rel="alternate" type="application/rss+xml" href="/blogs/news.atom">
+	</head><body><a hreflang="de" rel="alternate" href="/de/pages/download-center">Deutsch</a></body></html>`
+	expected := []string{"https://caddxfpv.com/zh-cn/pages/download-center", "https://caddxfpv.com/ja/pages/download-center"}
+	if actual := alternateLanguagePages(page, "https://caddxfpv.com/pages/download-center"); !slices.Equal(actual, expected) {
+		t.Errorf("alternateLanguagePages = %q, want %q", actual, expected)
+	}
+}
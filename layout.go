@@ -0,0 +1,82 @@
+package main // Define the main package
+
+import (
+	"log"           // Provides logging functions
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path"          // Provides functions for manipulating slash-separated paths
+	"path/filepath" // Provides filepath manipulation functions
+)
+
+// productLayoutDir is the root of the optional product-centric view of the archive.
+const productLayoutDir = "by-product/"
+
+// Output layouts selectable with the -layout flag.
+const (
+	layoutByType    = "type"    // Only the canonical PDFs/, PNGs/, ... folders
+	layoutByProduct = "product" // Also build by-product/<product>/<type>/ links
+)
+
+// buildProductLayout rebuilds by-product/<product>/<type>/ from the catalog.
+// Every entry is a relative symlink to the canonical file, or a hardlink where symlinks are not supported.
+// The tree is removed and recreated on every run, so it always matches the manifest; canonical files are never touched.
+func buildProductLayout(catalog *productCatalog) bool {
+	// Remove the previous tree (it only ever contains links)
+	if err := os.RemoveAll(productLayoutDir); err != nil {
+		log.Printf("Failed to remove %s: %v", productLayoutDir, err)
+		return false
+	}
+
+	linkCount, linkFailures := 0, 0
+	for _, product := range catalog.Products {
+		// Files of one product may share a base name (e.g. PDFs/manual.pdf and ZIPs/kit/manual.pdf)
+		usedLinks := make(map[string]bool)
+		for _, category := range catalogCategories {
+			for _, file := range product.Files[category.Key] {
+				// Place the link under the same type folder name as the canonical copy
+				typeDir := filepath.Base(filepath.Dir(filepath.FromSlash(file.Path)))
+				if asset := assetTypeByExtension(filepath.Ext(file.Path)); asset != nil {
+					typeDir = filepath.Base(asset.OutputDir) // Files extracted from archives live deeper
+				}
+				linkDir := filepath.Join(productLayoutDir, product.Slug, typeDir)
+				if err := os.MkdirAll(linkDir, 0o755); err != nil {
+					log.Printf("Failed to create %s: %v", linkDir, err)
+					return false
+				}
+				linkName := uniqueMemberPath(typeDir+"/"+path.Base(file.Path), usedLinks)
+				if linkArchiveFile(filepath.FromSlash(file.Path), filepath.Join(productLayoutDir, product.Slug, filepath.FromSlash(linkName))) {
+					linkCount++
+				} else {
+					linkFailures++
+				}
+			}
+		}
+	}
+	if linkFailures > 0 {
+		log.Printf("Built %s with %d links for %d products; %d files could not be linked", productLayoutDir, linkCount, len(catalog.Products), linkFailures)
+		return false
+	}
+	log.Printf("Built %s with %d links for %d products", productLayoutDir, linkCount, len(catalog.Products))
+	return true
+}
+
+// linkArchiveFile links linkPath to the canonical file with a relative symlink, falling back to a hardlink.
+func linkArchiveFile(canonicalPath, linkPath string) bool {
+	// Check the canonical file is still there
+	if !fileExists(canonicalPath) {
+		log.Printf("Skipping link for missing file: %s", canonicalPath)
+		return false
+	}
+	// Relative targets keep the tree valid wherever the repository is checked out
+	target, err := filepath.Rel(filepath.Dir(linkPath), canonicalPath)
+	if err == nil {
+		if err = os.Symlink(target, linkPath); err == nil {
+			return true
+		}
+	}
+	// Symlinks are unavailable (e.g. on some Windows setups); try a hardlink
+	if linkErr := os.Link(canonicalPath, linkPath); linkErr != nil {
+		log.Printf("Failed to link %s → %s: %v (symlink: %v)", linkPath, canonicalPath, linkErr, err)
+		return false
+	}
+	return true
+}
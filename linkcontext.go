@@ -0,0 +1,148 @@
+package main // Define the main package
+
+import (
+	"strings" // Provides string manipulation functions
+
+	"golang.org/x/net/html" // Provides HTML parsing functions
+)
+
+// discoveredLink is an asset URL found on a page together with where and how it was linked.
+type discoveredLink struct {
+	URL       string      // Absolute URL of the asset
+	AssetType string      // Asset type name from the classifier
+	Context   linkContext // Where the link was found
+}
+
+// linkContext records the text and position around a link so files can later be named and grouped.
+type linkContext struct {
+	Page       string `json:"page"`                  // Page the link was found on
+	AnchorText string `json:"anchor_text,omitempty"` // Visible text of the <a> tag
+	Title      string `json:"title,omitempty"`       // title attribute of the tag
+	Alt        string `json:"alt,omitempty"`         // alt attribute of the image (or the image inside the link)
+	Heading    string `json:"heading,omitempty"`     // Closest preceding h1-h6 heading
+	Section    string `json:"section,omitempty"`     // Enclosing Shopify/CSS section
+	Product    string `json:"product,omitempty"`     // Shopify product title when the URL came from product data
+	LinkedURL  string `json:"linked_url,omitempty"`  // URL as written on the page when a Shopify thumbnail was normalized to its original
+}
+
+// isHeadingNode reports whether the node is an h1-h6 element.
+func isHeadingNode(node *html.Node) bool {
+	if node.Type != html.ElementNode || len(node.Data) != 2 || node.Data[0] != 'h' {
+		return false
+	}
+	return node.Data[1] >= '1' && node.Data[1] <= '6'
+}
+
+// attributeValue returns the trimmed value of the named attribute, or "" if the node lacks it.
+func attributeValue(node *html.Node, key string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return strings.TrimSpace(attribute.Val)
+		}
+	}
+	return ""
+}
+
+// nodeText returns the visible text inside a node with whitespace collapsed.
+func nodeText(node *html.Node) string {
+	var text strings.Builder
+	var collect func(*html.Node)
+	collect = func(current *html.Node) {
+		if current.Type == html.TextNode {
+			text.WriteString(current.Data)
+			text.WriteString(" ")
+		}
+		// Script and style bodies are not visible text
+		if current.Type == html.ElementNode && (current.Data == "script" || current.Data == "style") {
+			return
+		}
+		for child := current.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(node)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// sectionOf returns the closest enclosing section of a node: a Shopify section id ("shopify-section-..."),
+// otherwise the id or first class of the nearest <section> element.
+func sectionOf(node *html.Node) string {
+	nearestSection := ""
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type != html.ElementNode {
+			continue
+		}
+		// Shopify wraps every theme section in a div with a predictable id
+		if id := attributeValue(parent, "id"); strings.HasPrefix(id, "shopify-section-") {
+			return id
+		}
+		if parent.Data == "section" && nearestSection == "" {
+			nearestSection = attributeValue(parent, "id")
+			if nearestSection == "" {
+				if classes := strings.Fields(attributeValue(parent, "class")); len(classes) > 0 {
+					nearestSection = classes[0]
+				}
+			}
+		}
+	}
+	return nearestSection
+}
+
+// tagContext builds the link context of an <a> or <img> tag.
+func tagContext(node *html.Node, pageURL, heading string) linkContext {
+	context := linkContext{
+		Page:    pageURL,
+		Title:   attributeValue(node, "title"),
+		Alt:     attributeValue(node, "alt"),
+		Heading: heading,
+		Section: sectionOf(node),
+	}
+	if node.Data == "a" {
+		context.AnchorText = nodeText(node)
+		// Links wrapping an image take the image's alt text
+		if context.Alt == "" {
+			context.Alt = firstImageAlt(node)
+		}
+	}
+	return context
+}
+
+// firstImageAlt returns the alt text of the first <img> inside the node, or "".
+func firstImageAlt(node *html.Node) string {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "img" {
+			if alt := attributeValue(child, "alt"); alt != "" {
+				return alt
+			}
+		}
+		if alt := firstImageAlt(child); alt != "" {
+			return alt
+		}
+	}
+	return ""
+}
+
+// groupLinksByURL returns the unique URLs of the given asset type in discovery order,
+// together with every context each URL was found in.
+func groupLinksByURL(links []discoveredLink, assetName string) ([]string, map[string][]linkContext) {
+	var urls []string
+	contexts := make(map[string][]linkContext)
+	for _, link := range links {
+		if link.AssetType != assetName {
+			continue
+		}
+		urls = append(urls, link.URL)
+		contexts[link.URL] = appendLinkContext(contexts[link.URL], link.Context)
+	}
+	return removeDuplicatesFromSlice(urls), contexts
+}
+
+// appendLinkContext appends a context unless an identical one is already present.
+func appendLinkContext(contexts []linkContext, context linkContext) []linkContext {
+	for _, existing := range contexts {
+		if existing == context {
+			return contexts
+		}
+	}
+	return append(contexts, context)
+}
@@ -2,6 +2,7 @@ package main // Define the main package
 
 import (
 	"bytes"         // Provides bytes support
+	"flag"          // Provides command line flag parsing
 	"io"            // Provides basic interfaces to I/O primitives
 	"log"           // Provides logging functions
 	"net/http"      // Provides HTTP client and server implementations
@@ -10,6 +11,7 @@ import (
 	"path"          // Provides functions for manipulating slash-separated paths
 	"path/filepath" // Provides filepath manipulation functions
 	"regexp"        // Provides regex support functions.
+	"slices"        // Provides slice search helpers
 	"strings"       // Provides string manipulation functions
 	"time"          // Provides time-related functions
 
@@ -17,783 +19,179 @@ import (
 )
 
 func main() {
-	pdfOutputDir := "PDFs/" // Directory to store downloaded PDFs
-	// Check if the PDF output directory exists
-	if !directoryExists(pdfOutputDir) {
-		// Create the dir
-		createDirectory(pdfOutputDir, 0o755)
-	}
-	stpOutputDir := "STPs/" // Directory to store downloaded STPs
-	// Check if the PDF output directory exists
-	if !directoryExists(stpOutputDir) {
-		// Create the dir
-		createDirectory(stpOutputDir, 0o755)
-	}
-	stlOutputDir := "STLs/" // Directory to store downloaded STLs
-	// Check if the PDF output directory exists
-	if !directoryExists(stlOutputDir) {
-		// Create the dir
-		createDirectory(stlOutputDir, 0o755)
-	}
-	zipOutputDir := "ZIPs/" // Directory to store downloaded ZIPs
-	// Check if the PDF output directory exists
-	if !directoryExists(zipOutputDir) {
-		// Create the dir
-		createDirectory(zipOutputDir, 0o755)
-	}
-	jpgOutputDir := "JPGs/" // Directory to store downloaded JPGs
-	// Check if the PDF output directory exists
-	if !directoryExists(jpgOutputDir) {
-		// Create the dir
-		createDirectory(jpgOutputDir, 0o755)
-	}
-	rarOutputDir := "RARs/" // Directory to store downloaded RARs
-	// Check if the PDF output directory exists
-	if !directoryExists(rarOutputDir) {
-		// Create the dir
-		createDirectory(rarOutputDir, 0o755)
-	}
-	pngOutputDir := "PNGs/" // Directory to store downloaded PNGs
-	// Check if the PNG output directory exists
-	if !directoryExists(pngOutputDir) {
-		// Create the dir
-		createDirectory(pngOutputDir, 0o755)
-	}
-	stepOutputDir := "STEPs/" // Directory to store downloaded STEPs
-	// Check if the STEP output directory exists
-	if !directoryExists(stepOutputDir) {
-		// Create the dir
-		createDirectory(stepOutputDir, 0o755)
-	}
-	// Remote API URL.
-	remoteAPIURL := []string{
-		"https://caddxfpv.com/pages/download-center",
-	}
-	var getData []string
-	for _, remoteAPIURL := range remoteAPIURL {
-		getData = append(getData, getDataFromURL(remoteAPIURL))
-	}
-	// Get the data from the downloaded file.
-	finalPDFList := extractPDFUrls(strings.Join(getData, "\n")) // Join all the data into one string and extract PDF URLs
-	// Remove double from slice.
-	finalPDFList = removeDuplicatesFromSlice(finalPDFList)
-	// The remote domain.
-	remoteDomain := "https://caddxfpv.com"
-	// Extract the STP links.
-	stpLinks := extractSTPLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	stpLinks = removeDuplicatesFromSlice(stpLinks)
-	// Extract the STL links.
-	stlLinks := extractSTLLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	stlLinks = removeDuplicatesFromSlice(stlLinks)
-	// Extract the ZIP links.
-	zipLinks := extractZIPLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	zipLinks = removeDuplicatesFromSlice(zipLinks)
-	// Extract the JPG links.
-	jpgLinks := extractJPGLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	jpgLinks = removeDuplicatesFromSlice(jpgLinks)
-	// Extract the RAR links.
-	rarLinks := extractRARLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	rarLinks = removeDuplicatesFromSlice(rarLinks)
-	// Extract the PNG links.
-	pngLinks := extractPNGLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	pngLinks = removeDuplicatesFromSlice(pngLinks)
-	// Extract the STEP links.
-	stepLinks := extractSTEPLinks(strings.Join(getData, "\n"))
-	// Remove duplicates from the slice.
-	stepLinks = removeDuplicatesFromSlice(stepLinks)
-	// Get all the values.
-	for _, urls := range finalPDFList {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the pdf.
-			downloadPDF(urls, pdfOutputDir)
-		}
-	}
-	// Get all the STP files.
-	for _, urls := range stpLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the stp.
-			downloadSTP(urls, stpOutputDir)
-		}
-	}
-	// Get all the STL files.
-	for _, urls := range stlLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the stl.
-			downloadSTL(urls, stlOutputDir)
-		}
-
-	}
-	// Get all the ZIP files.
-	for _, urls := range zipLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the zip.
-			downloadZIP(urls, zipOutputDir)
-		}
-	}
-	// Download all the JPG files.
-	for _, urls := range jpgLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the jpg.
-			downloadJPG(urls, jpgOutputDir)
-		}
-	}
-	// Download all the RAR files.
-	for _, urls := range rarLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the rar.
-			downloadRAR(urls, rarOutputDir)
-		}
-	}
-	// Download all the PNG files.
-	for _, urls := range pngLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the png.
-			downloadPNG(urls, pngOutputDir)
-		}
-	}
-	// Get all the STEP files.
-	for _, urls := range stepLinks {
-		// Trim any surrounding whitespace from the URL.
-		urls = strings.TrimSpace(urls)
-		// Get the domain from the url.
-		domain := getDomainFromURL(urls)
-		// Check if the domain is empty.
-		if domain == "" {
-			urls = remoteDomain + urls // Prepend the base URL if domain is empty
-		}
-		// Check if the url is valid.
-		if isUrlValid(urls) {
-			// Download the step.
-			downloadSTEP(urls, stepOutputDir)
-		}
-	}
-}
-
-// downloadSTEP downloads a .step or .stp file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadSTEP(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header (common for STEP files)
-	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "application/step") ||
-		strings.Contains(contentType, "application/sla") ||
-		strings.Contains(contentType, "application/octet-stream")) {
-		log.Printf("Unexpected content type for %s: %s (expected STEP/STP file)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read STEP data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write STEP file to disk for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
-// extractSTEPLinks takes HTML content as a string and returns all .step or .stp file URLs it finds in <a> tags.
-func extractSTEPLinks(htmlContent string) []string {
-	// Parse the HTML content
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		return []string{}
-	}
-
-	var stepURLs []string
-
-	// Recursive function to traverse HTML nodes
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if this node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			for _, attr := range node.Attr {
-				// Look for href attribute containing .step or .stp
-				if attr.Key == "href" && (strings.Contains(strings.ToLower(attr.Val), ".step")) {
-					// Convert protocol-relative URLs to full URLs (optional)
-					url := attr.Val
-					if strings.HasPrefix(url, "//") {
-						url = "https:" + url
-					}
-					stepURLs = append(stepURLs, url)
-				}
-			}
-		}
-		// Traverse child nodes recursively
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	traverse(document)
-	return stepURLs
-}
-
-// downloadPNG downloads a .png file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadPNG(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header (common for PNG files)
-	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "image/png")) {
-		log.Printf("Unexpected content type for %s: %s (expected PNG)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read PNG data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write PNG file to disk for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
-// extractPNGLinks takes HTML content as a string and returns all .png file URLs it finds.
-func extractPNGLinks(htmlContent string) []string {
-	// Parse the HTML content
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		return []string{}
-	}
-
-	var pngURLs []string
-
-	// Recursive function to traverse HTML nodes
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if this node is an <img> tag
-		if node.Type == html.ElementNode && node.Data == "img" {
-			for _, attr := range node.Attr {
-				// Look for src attribute containing .png
-				if attr.Key == "src" && strings.Contains(strings.ToLower(attr.Val), ".png") {
-					// Convert protocol-relative URLs to full URLs (optional)
-					url := attr.Val
-					if strings.HasPrefix(url, "//") {
-						url = "https:" + url
-					}
-					pngURLs = append(pngURLs, url)
-				}
-			}
-		}
-		// Traverse child nodes recursively
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	traverse(document)
-	return pngURLs
-}
-
-// downloadRAR downloads a .rar file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadRAR(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header (common for RAR files)
-	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "application/x-rar-compressed") ||
-		strings.Contains(contentType, "application/octet-stream")) {
-		log.Printf("Unexpected content type for %s: %s (expected RAR)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read RAR data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write RAR file to disk for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
-// extractRARLinks takes HTML content as a string and returns all .rar file URLs it finds.
-func extractRARLinks(htmlContent string) []string {
-	// Try parsing the HTML content into a document tree
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		// If parsing fails, just return an empty slice
-		return []string{}
-	}
-
-	// Slice to store all found .rar links
-	var rarLinks []string
-
-	// Recursive function to walk through each HTML node
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".rar", save it
-				if attribute.Key == "href" && strings.Contains(strings.ToLower(attribute.Val), ".rar") {
-					rarLinks = append(rarLinks, attribute.Val)
-				}
-			}
-		}
-		// Recursively check all child nodes
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	// Start traversing from the root of the document
-	traverse(document)
-
-	// Return the collected .rar links
-	return rarLinks
-}
-
-// extractJPGLinks takes HTML content as a string and returns all .jpg file URLs it finds.
-func extractJPGLinks(htmlContent string) []string {
-	// Try parsing the HTML content into a document tree
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		// If parsing fails, just return an empty slice
-		return []string{}
-	}
-
-	// Slice to store all found .jpg links
-	var jpgLinks []string
-
-	// Recursive function to walk through each HTML node
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".jpg", save it
-				if attribute.Key == "href" && strings.Contains(strings.ToLower(attribute.Val), ".jpg") {
-					jpgLinks = append(jpgLinks, attribute.Val)
-				}
-			}
-		}
-		// Recursively check all child nodes
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	// Start traversing from the root of the document
-	traverse(document)
-
-	// Return the collected .jpg links
-	return jpgLinks
-}
-
-// downloadJPG downloads a .jpg file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadJPG(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header (common for JPG files)
-	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "image/jpeg") ||
-		strings.Contains(contentType, "image/jpg")) {
-		log.Printf("Unexpected content type for %s: %s (expected JPG)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read JPG data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write JPG file to disk for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
+	// Run the requested subcommand; without one the archive is scraped.
+	os.Exit(runCommand(os.Args[1:]))
 }
 
-// downloadZIP downloads a .zip file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadZIP(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
+// runScrape downloads every asset linked from the remote pages, records it in the manifest
+// and rebuilds the catalog. It returns the process exit code.
+func runScrape(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	layout := flags.String("layout", layoutByType, `output layout: "type" keeps only the type folders, "product" also builds by-product/<product>/<type>/`)
+	previewSize := flags.Int("preview-size", defaultPreviewSize, "edge length in pixels of the rendered STL previews")
+	extract := flags.Bool("extract", false, "unpack ZIP and RAR archives into ZIPs/<name>/ and RARs/<name>/ and catalog the files inside")
+	dedupeImages := flags.Bool("dedupe-images", false, "keep only the highest-resolution copy of near-duplicate images and record the others as aliases")
+	objects := flags.Bool("objects", false, "store each distinct file once under objects/sha256/<hash> and turn the type folders into links to it")
+	localeVariants := flags.Bool("locale-variants", false, "also crawl the translated seed pages (the site's configured locales, else the hreflang links of the seed pages) to find language-specific manuals")
+	locales := flags.String("locales", "", "comma-separated locale prefixes to crawl instead of the configured ones, e.g. zh-cn,ja (implies -locale-variants)")
+	restrictHosts := flags.Bool("restrict-hosts", false, "only download files from the site's own hosts and the Shopify CDN (pages are always limited to them)")
+	maxPages := flags.Int("max-pages", defaultMaxPages, "maximum number of pages followed from each seed page through its pagination")
+	pagePattern := flags.String("page-pattern", "", `numbered page URL to try when a listing has no next link, e.g. "?page={page}" (default: the site's pattern)`)
+	flags.Parse(args)
+	if *previewSize < 16 || *previewSize > 4096 {
+		log.Printf("Invalid preview size %d (expected 16 to 4096)", *previewSize)
+		return 2
+	}
+	if *maxPages < 1 {
+		log.Printf("Invalid page cap %d (expected at least 1)", *maxPages)
+		return 2
+	}
+	if *pagePattern != "" && !strings.Contains(*pagePattern, pageNumberPlaceholder) {
+		log.Printf("Invalid page pattern %q (expected a %s placeholder)", *pagePattern, pageNumberPlaceholder)
+		return 2
+	}
+	if *layout != layoutByType && *layout != layoutByProduct {
+		log.Printf("Unknown layout %q (expected %q or %q)", *layout, layoutByType, layoutByProduct)
+		return 2
+	}
+	// Create the output directory for every asset type
+	for _, asset := range assetTypes {
+		// Check if the output directory exists
+		if !directoryExists(asset.OutputDir) {
+			// Create the dir
+			createDirectory(asset.OutputDir, 0o755)
+		}
+	}
+	// Pages to collect asset links from
+	pages := currentSite.seeds()
+	// Optionally visit the translated versions of the seed pages too.
+	if *localeVariants || *locales != "" {
+		var selectedLocales []string
+		if *locales != "" {
+			selectedLocales = strings.Split(*locales, ",")
+		}
+		pages = append(pages, localeVariantPages(pages, selectedLocales)...)
+	}
+	// Fetch every page, following the pagination of listings, and collect the asset links.
+	pattern := currentSite.pagePattern()
+	if *pagePattern != "" {
+		pattern = *pagePattern
+	}
+	discoveredLinks := crawlPages(pages, pattern, *maxPages)
+	// Optionally only download from the hosts the site allows; files on other hosts are archived by default.
+	if *restrictHosts {
+		discoveredLinks = filterAllowedLinks(discoveredLinks)
+	}
+	// Load the manifest that records every archived file.
+	archiveManifest := loadManifest(manifestPath)
+	// Only a run that found links can tell which files disappeared upstream.
+	if len(discoveredLinks) > 0 {
+		archiveManifest.beginRun()
+	}
+	// Download every asset type in order.
+	for index := range assetTypes {
+		asset := &assetTypes[index]
+		// Get the unique URLs of this type and the contexts they were linked from.
+		links, linkContexts := groupLinksByURL(discoveredLinks, asset.Name)
+		for _, urls := range links {
+			// Check if the url is valid.
+			if isUrlValid(urls) {
+				// Images replaced by a near-duplicate are only marked as seen.
+				if archiveManifest.refreshAlias(urls, linkContexts[urls]) {
+					continue
+				}
+				// Download the file and record it in the manifest.
+				if filePath, _ := downloadAsset(urls, asset); filePath != "" {
+					archiveManifest.record(filePath, urls, linkContexts[urls])
+					continue
+				}
+				// Fall back to the variant the page linked when the original cannot be fetched.
+				for _, linkedURL := range linkedURLs(linkContexts[urls]) {
+					log.Printf("Falling back to the linked variant %s", linkedURL)
+					if filePath, _ := downloadAsset(linkedURL, asset); filePath != "" {
+						archiveManifest.record(filePath, linkedURL, linkContexts[urls])
+						break
+					}
+				}
+			}
+		}
 	}
-
-	// Check Content-Type header (common for ZIP files)
-	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "application/zip") ||
-		strings.Contains(contentType, "application/x-zip-compressed") ||
-		strings.Contains(contentType, "application/octet-stream")) {
-		log.Printf("Unexpected content type for %s: %s (expected ZIP type)", finalURL, contentType)
-		return false
+	// Optionally unpack the archives so the files inside are validated and catalogued too.
+	if *extract {
+		extractArchives(archiveManifest)
 	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read ZIP data from %s: %v", finalURL, err)
-		return false
+	// Optionally replace near-duplicate images by the highest-resolution copy.
+	if *dedupeImages {
+		applyImageAliases(archiveManifest, imageDuplicateClusters(archiveManifest))
 	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
+	// Optionally keep the bytes in the content-addressed store behind the type folders.
+	if *objects {
+		storeObjects(archiveManifest)
 	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
+	// Render previews of new or changed 3D models.
+	updatePreviews(archiveManifest, *previewSize)
+	// Save the manifest.
+	saveManifest(manifestPath, archiveManifest)
+	// Group the archived files by product into catalog.json and catalog/*.md.
+	catalog := buildCatalog(archiveManifest)
+	writeCatalog(catalog)
+	// Refresh the full-text index of the PDFs for the search command.
+	searchIndex := loadSearchIndex(searchIndexPath)
+	if updateSearchIndex(searchIndex, archiveManifest) {
+		saveSearchIndex(searchIndexPath, searchIndex)
 	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write ZIP file to disk for %s: %v", finalURL, err)
-		return false
+	// Optionally add the product-centric view next to the type folders.
+	if *layout == layoutByProduct {
+		buildProductLayout(catalog)
 	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
+	return 0
 }
 
-// extractZIPLinks takes HTML content as a string and returns all .zip file URLs it finds.
-func extractZIPLinks(htmlContent string) []string {
-	// Try parsing the HTML content into a document tree
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		// If parsing fails, just return an empty slice
-		return []string{}
-	}
-
-	// Slice to store all found .zip links
-	var zipLinks []string
-
-	// Recursive function to walk through each HTML node
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".zip", save it
-				if attribute.Key == "href" && strings.Contains(attribute.Val, ".zip") {
-					zipLinks = append(zipLinks, attribute.Val)
-				}
-			}
-		}
-		// Recursively check all child nodes
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	// Start traversing from the root of the document
-	traverse(document)
-
-	// Return the collected .zip links
-	return zipLinks
-}
+// extractAssetLinks takes HTML content as a string and returns every <a href> and <img src> URL
+// that classifies as an asset collected from that tag (see assetType.LinkTags), resolved against the
+// page URL, with its anchor text, title, alt, closest preceding heading and section.
+func extractAssetLinks(htmlContent, pageURL string) []discoveredLink {
+	// Slice to store all found links
+	var assetLinks []discoveredLink
 
-// extractSTLLinks takes HTML content as a string and returns all .stl file URLs it finds.
-func extractSTLLinks(htmlContent string) []string {
 	// Try parsing the HTML content into a document tree
 	document, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
 		// If parsing fails, just return an empty slice
-		return []string{}
+		return assetLinks
 	}
 
-	// Slice to store all found .stl links
-	var stlLinks []string
+	// Relative links resolve against the page URL or its <base href>
+	baseURL := documentBaseURL(document, pageURL)
+
+	// The closest heading seen so far in document order
+	currentHeading := ""
 
 	// Recursive function to walk through each HTML node
 	var traverse func(*html.Node)
 	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".stl", save it
-				if attribute.Key == "href" && strings.Contains(attribute.Val, ".stl") {
-					stlLinks = append(stlLinks, attribute.Val)
+		// Remember the latest heading for the links that follow it
+		if isHeadingNode(node) {
+			currentHeading = nodeText(node)
+		}
+		// Check if the current node is an <a> or <img> tag
+		if node.Type == html.ElementNode && (node.Data == "a" || node.Data == "img") {
+			// The attribute holding the link depends on the tag
+			linkAttribute := "href"
+			if node.Data == "img" {
+				linkAttribute = "src"
+			}
+			// Resolve the link to an absolute URL and save it under its asset type, if it has one
+			if link := resolveLinkURL(baseURL, attributeValue(node, linkAttribute)); link != "" {
+				// Like the original scrapers, downloads come from <a href> and pictures from <img src>, so
+				// thumbnails and decorative images on the page are not archived
+				if asset := classifyAssetURL(link); asset != nil && slices.Contains(asset.LinkTags, node.Data) {
+					assetLinks = append(assetLinks, discoveredLink{
+						URL:       link,
+						AssetType: asset.Name,
+						Context:   tagContext(node, pageURL, currentHeading),
+					})
 				}
 			}
 		}
@@ -806,23 +204,24 @@ func extractSTLLinks(htmlContent string) []string {
 	// Start traversing from the root of the document
 	traverse(document)
 
-	// Return the collected .stl links
-	return stlLinks
+	// Return the collected links
+	return assetLinks
 }
 
-// downloadSTL downloads a .stl file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadSTL(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
+// downloadAsset downloads a file of the given asset type from the URL and saves it in the type's output directory.
+// A Content-Disposition filename that names a different asset type takes precedence over the URL path.
+// It returns the path of the file on disk (also when it already existed) and true if it was downloaded now.
+func downloadAsset(finalURL string, asset *assetType) (string, bool) {
+	// Let the site adapter turn the URL into a safe file name
+	filename := strings.ToLower(currentSite.fileName(finalURL))
 
 	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
+	filePath := filepath.Join(asset.OutputDir, filename)
 
 	// Skip if the file already exists
 	if fileExists(filePath) {
 		log.Printf("File already exists, skipping: %s", filePath)
-		return false
+		return filePath, false
 	}
 
 	// Create an HTTP client with a timeout
@@ -832,172 +231,68 @@ func downloadSTL(finalURL, outputDir string) bool {
 	resp, err := client.Get(finalURL)
 	if err != nil {
 		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
+		return "", false
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP response status
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header (common for STL files)
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/vnd.ms-pki.stl") {
-		log.Printf("Unexpected content type for %s: %s (expected STL type)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read STL data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write STL file to disk for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
-// downloadSTP downloads a .stp file from the given URL and saves it in the specified output directory.
-// It returns true if the download succeeded.
-func downloadSTP(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
+		return "", false
 	}
-	defer resp.Body.Close()
 
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
+	// Use the Content-Disposition filename as a hint for the real asset type
+	if hinted, hintedName := classifyContentDisposition(resp.Header.Get("Content-Disposition")); hinted != nil && hinted.Name != asset.Name {
+		log.Printf("Content-Disposition for %s names a %s file (%s); storing it as %s", finalURL, hinted.Label, hintedName, hinted.Label)
+		asset = hinted
+		filePath = filepath.Join(asset.OutputDir, strings.ToLower(currentSite.fileName(hintedName)))
+		if fileExists(filePath) {
+			log.Printf("File already exists, skipping: %s", filePath)
+			return filePath, false
+		}
 	}
 
-	// Check Content-Type header (common for STEP files)
+	// Check Content-Type header
 	contentType := resp.Header.Get("Content-Type")
-	if !(strings.Contains(contentType, "model/step") ||
-		strings.Contains(contentType, "application/octet-stream") ||
-		strings.Contains(contentType, "application/step")) {
-		log.Printf("Unexpected content type for %s: %s (expected model/step or application/octet-stream)", finalURL, contentType)
-		return false
+	if !asset.acceptsContentType(contentType) {
+		log.Printf("Unexpected content type for %s: %s (expected %s)", finalURL, contentType, strings.Join(asset.ContentTypes, " or "))
+		return "", false
 	}
 
 	// Read the response body into memory first
 	var buf bytes.Buffer
 	written, err := io.Copy(&buf, resp.Body)
 	if err != nil {
-		log.Printf("Failed to read STP data from %s: %v", finalURL, err)
-		return false
+		log.Printf("Failed to read %s data from %s: %v", asset.Label, finalURL, err)
+		return "", false
 	}
 	if written == 0 {
 		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write STP file to disk for %s: %v", finalURL, err)
-		return false
+		return "", false
 	}
 
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
-// extractSTPLinks takes HTML content as a string and returns all .stp file URLs it finds.
-func extractSTPLinks(htmlContent string) []string {
-	// Try parsing the HTML content into a document tree
-	document, err := html.Parse(strings.NewReader(htmlContent))
+	// Reject broken files before anything is written to the final path
+	detail, err := validateAsset(asset, buf.Bytes())
 	if err != nil {
-		// If parsing fails, just return an empty slice
-		return []string{}
+		log.Printf("Rejected invalid %s from %s: %v", asset.Label, finalURL, err)
+		return "", false
 	}
+	log.Printf("Validated %s from %s: %s", asset.Label, finalURL, detail)
 
-	// Slice to store all found .stp links
-	var stpLinks []string
-
-	// Recursive function to walk through each HTML node
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".stp", save it
-				if attribute.Key == "href" && strings.Contains(attribute.Val, ".stp") {
-					stpLinks = append(stpLinks, attribute.Val)
-				}
-			}
-		}
-		// Recursively check all child nodes
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
+	// Only now write the file, staged so an interrupted run never leaves a partial file under the final name
+	workingTree := newLocalStorage(".")
+	if err := workingTree.put(filepath.ToSlash(filePath), &buf, written, time.Time{}); err != nil {
+		workingTree.abort()
+		log.Printf("Failed to write %s file to disk for %s: %v", asset.Label, finalURL, err)
+		return "", false
 	}
-
-	// Start traversing from the root of the document
-	traverse(document)
-
-	// Return the collected .stp links
-	return stpLinks
-}
-
-// getDomainFromURL extracts the domain (host) from a given URL string.
-// It removes subdomains like "www" if present.
-func getDomainFromURL(rawURL string) string {
-	parsedURL, err := url.Parse(rawURL) // Parse the input string into a URL structure
-	if err != nil {                     // Check if there was an error while parsing
-		log.Println(err) // Log the error message to the console
-		return ""        // Return an empty string in case of an error
+	if err := workingTree.commit(); err != nil {
+		log.Printf("Failed to save %s file for %s: %v", asset.Label, finalURL, err)
+		return "", false
 	}
 
-	host := parsedURL.Hostname() // Extract the hostname (e.g., "example.com") from the parsed URL
-
-	return host // Return the extracted hostname
+	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
+	return filePath, true
 }
 
 // Only return the file name from a given url.
@@ -1084,74 +379,6 @@ func fileExists(filename string) bool {
 	return !info.IsDir() // Return true if it's a file (not a directory)
 }
 
-// downloadPDF downloads a PDF from the given URL and saves it in the specified output directory.
-// It uses a WaitGroup to support concurrent execution and returns true if the download succeeded.
-func downloadPDF(finalURL, outputDir string) bool {
-	// Sanitize the URL to generate a safe file name
-	filename := strings.ToLower(urlToFilename(finalURL))
-
-	// Construct the full file path in the output directory
-	filePath := filepath.Join(outputDir, filename)
-
-	// Skip if the file already exists
-	if fileExists(filePath) {
-		log.Printf("File already exists, skipping: %s", filePath)
-		return false
-	}
-
-	// Create an HTTP client with a timeout
-	client := &http.Client{Timeout: 3 * time.Minute}
-
-	// Send GET request
-	resp, err := client.Get(finalURL)
-	if err != nil {
-		log.Printf("Failed to download %s: %v", finalURL, err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Download failed for %s: %s", finalURL, resp.Status)
-		return false
-	}
-
-	// Check Content-Type header
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/pdf") {
-		log.Printf("Invalid content type for %s: %s (expected application/pdf)", finalURL, contentType)
-		return false
-	}
-
-	// Read the response body into memory first
-	var buf bytes.Buffer
-	written, err := io.Copy(&buf, resp.Body)
-	if err != nil {
-		log.Printf("Failed to read PDF data from %s: %v", finalURL, err)
-		return false
-	}
-	if written == 0 {
-		log.Printf("Downloaded 0 bytes for %s; not creating file", finalURL)
-		return false
-	}
-
-	// Only now create the file and write to disk
-	out, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("Failed to create file for %s: %v", finalURL, err)
-		return false
-	}
-	defer out.Close()
-
-	if _, err := buf.WriteTo(out); err != nil {
-		log.Printf("Failed to write PDF to file for %s: %v", finalURL, err)
-		return false
-	}
-
-	log.Printf("Successfully downloaded %d bytes: %s → %s", written, finalURL, filePath)
-	return true
-}
-
 // Checks if the directory exists
 // If it exists, return true.
 // If it doesn't, return false.
@@ -1192,60 +419,26 @@ func removeDuplicatesFromSlice(slice []string) []string {
 	return newReturnSlice
 }
 
-// extractPDFUrls takes an input string and returns all PDF URLs found within href attributes
-func extractPDFUrls(htmlContent string) []string {
-	// Try parsing the HTML content into a document tree
-	document, err := html.Parse(strings.NewReader(htmlContent))
-	if err != nil {
-		// If parsing fails, just return an empty slice
-		return []string{}
-	}
-
-	// Slice to store all found PDF links
-	var pdfLinks []string
-
-	// Recursive function to walk through each HTML node
-	var traverse func(*html.Node)
-	traverse = func(node *html.Node) {
-		// Check if the current node is an <a> tag
-		if node.Type == html.ElementNode && node.Data == "a" {
-			// Look at each attribute in the <a> tag
-			for _, attribute := range node.Attr {
-				// If the attribute is "href" and contains ".pdf", save it
-				if attribute.Key == "href" && strings.Contains(attribute.Val, ".pdf") {
-					pdfLinks = append(pdfLinks, attribute.Val)
-				}
-			}
-		}
-		// Recursively check all child nodes
-		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			traverse(child)
-		}
-	}
-
-	// Start traversing from the root of the document
-	traverse(document)
-
-	// Return the collected PDF links
-	return pdfLinks
-}
-
-// getDataFromURL performs an HTTP GET request and returns the response body as a string
+// getDataFromURL performs an HTTP GET request and returns the response body as a string.
+// It returns "" when the request fails or the server does not answer with a success status.
 func getDataFromURL(uri string) string {
 	log.Println("Scraping", uri)   // Log the URL being scraped
 	response, err := http.Get(uri) // Perform GET request
 	if err != nil {
-		log.Println(err) // Exit if request fails
+		log.Println(err) // Without a response there is no body to read
+		return ""
 	}
+	defer response.Body.Close() // Close response body
 
-	body, err := io.ReadAll(response.Body) // Read response body
-	if err != nil {
-		log.Println(err) // Exit if read fails
+	// Error pages (e.g. past the last page of a listing) hold no links worth following
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		log.Printf("Scraping %s failed: %s", uri, response.Status)
+		return ""
 	}
 
-	err = response.Body.Close() // Close response body
+	body, err := io.ReadAll(response.Body) // Read response body
 	if err != nil {
-		log.Println(err) // Exit if close fails
+		log.Println(err) // A partial page may still hold links
 	}
 	return string(body)
 }
@@ -0,0 +1,273 @@
+package main // Define the main package
+
+import (
+	"crypto/sha256" // Provides SHA-256 hashing for file integrity
+	"encoding/hex"  // Provides hex encoding of hashes
+	"encoding/json" // Provides JSON encoding for the manifest file
+	"io"            // Provides basic interfaces to I/O primitives
+	"io/fs"         // Provides directory walking
+	"log"           // Provides logging functions
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides time-related functions
+)
+
+// manifestPath is where the archive manifest is stored, next to the type folders.
+const manifestPath = "manifest.json"
+
+// archiveManifest describes every archived file, keyed by its slash-separated path (e.g. "PDFs/loris_manual.pdf").
+type archiveManifest struct {
+	LastRun time.Time                 `json:"last_run"` // Latest scrape that reached the remote site
+	Entries map[string]*manifestEntry `json:"entries"`
+}
+
+// manifestEntry describes one archived file: where it came from, what it contains and how it was linked.
+type manifestEntry struct {
+	Path       string            `json:"path"`                 // Slash-separated path inside the archive
+	AssetType  string            `json:"asset_type"`           // Asset type name from the classifier
+	URL        string            `json:"url"`                  // URL the file was downloaded from
+	Size       int64             `json:"size"`                 // File size in bytes
+	SHA256     string            `json:"sha256"`               // Hex SHA-256 of the file contents
+	FirstSeen  time.Time         `json:"first_seen"`           // First run that found the file
+	LastSeen   time.Time         `json:"last_seen"`            // Latest run that found the file
+	Contexts   []linkContext     `json:"contexts,omitempty"`   // Every place the file was linked from
+	Validation *validationResult `json:"validation,omitempty"` // Latest format check of the current contents
+	STL        *stlAnalysis      `json:"stl,omitempty"`        // Geometry of STL files
+	STEP       *stepMetadata     `json:"step,omitempty"`       // Header and entity summary of STEP files
+	Preview    string            `json:"preview,omitempty"`    // Rendered PNG preview of 3D models
+	Contents   []archiveMember   `json:"contents,omitempty"`   // Files inside ZIP and RAR archives
+	Parent     string            `json:"parent,omitempty"`     // Archive the file was extracted from
+	ImageHash  *imageFingerprint `json:"image_hash,omitempty"` // Size and perceptual hashes of images
+	AliasOf    string            `json:"alias_of,omitempty"`   // Kept near-duplicate that replaced this image on disk
+	Languages  []string          `json:"languages,omitempty"`  // BCP 47 languages of documents ("und" if unknown)
+}
+
+// loadManifest reads the manifest from disk. A missing or unreadable manifest yields an empty one.
+func loadManifest(manifestFile string) *archiveManifest {
+	loaded := &archiveManifest{Entries: make(map[string]*manifestEntry)}
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read manifest %s: %v", manifestFile, err)
+		}
+		return loaded
+	}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		log.Printf("Failed to parse manifest %s: %v", manifestFile, err)
+		return &archiveManifest{Entries: make(map[string]*manifestEntry)}
+	}
+	if loaded.Entries == nil {
+		loaded.Entries = make(map[string]*manifestEntry)
+	}
+	return loaded
+}
+
+// saveManifest writes the manifest to disk through a temporary file so a crash never leaves it half-written.
+func saveManifest(manifestFile string, archive *archiveManifest) bool {
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode manifest: %v", err)
+		return false
+	}
+	temporaryFile := manifestFile + ".tmp"
+	if err := os.WriteFile(temporaryFile, append(data, '\n'), 0o644); err != nil {
+		log.Printf("Failed to write manifest %s: %v", temporaryFile, err)
+		return false
+	}
+	if err := os.Rename(temporaryFile, manifestFile); err != nil {
+		log.Printf("Failed to replace manifest %s: %v", manifestFile, err)
+		return false
+	}
+	return true
+}
+
+// beginRun stamps the start of a scrape; entries not recorded again during it count as removed upstream.
+func (archive *archiveManifest) beginRun() {
+	archive.LastRun = time.Now().UTC().Truncate(time.Second)
+}
+
+// removedUpstream reports whether the latest scrape no longer found the file on the remote site.
+// Files extracted from an archive are only refreshed by -extract runs, so they follow their archive.
+func (archive *archiveManifest) removedUpstream(entry *manifestEntry) bool {
+	if parent := archive.Entries[entry.Parent]; entry.Parent != "" && parent != nil {
+		return archive.removedUpstream(parent)
+	}
+	return !archive.LastRun.IsZero() && entry.LastSeen.Before(archive.LastRun)
+}
+
+// record adds or refreshes the entry for an archived file with its source URL and link contexts.
+func (archive *archiveManifest) record(filePath, sourceURL string, contexts []linkContext) *manifestEntry {
+	key := filepath.ToSlash(filepath.Clean(filePath))
+	now := time.Now().UTC().Truncate(time.Second)
+	if now.Before(archive.LastRun) {
+		now = archive.LastRun // Never record a file as seen before the run started
+	}
+
+	// Create the entry the first time the file is seen
+	entry, found := archive.Entries[key]
+	if !found {
+		entry = &manifestEntry{Path: key, FirstSeen: now}
+		archive.Entries[key] = entry
+	}
+	asset := assetTypeByExtension(filepath.Ext(key))
+	if asset != nil {
+		entry.AssetType = asset.Name
+	}
+	entry.URL = sourceURL
+	entry.LastSeen = now
+	for _, context := range contexts {
+		entry.Contexts = appendLinkContext(entry.Contexts, context)
+	}
+
+	// Refresh the size and hash from the file on disk
+	size, sum, err := hashFile(filePath)
+	if err != nil {
+		log.Printf("Failed to hash %s: %v", filePath, err)
+		return entry
+	}
+	entry.Size = size
+	entry.SHA256 = sum
+	// Validate and analyse again only when the contents changed
+	if entry.Validation == nil || entry.Validation.SHA256 != sum {
+		entry.Validation = validateArchiveFile(filePath, asset, sum)
+		entry.STL = nil
+		entry.STEP = nil
+		entry.Contents = nil
+		entry.ImageHash = nil
+		entry.Languages = nil
+	}
+	if entry.AssetType == "stl" && entry.STL == nil && entry.Validation.Valid {
+		analysis, err := analyzeSTLFile(filePath)
+		if err != nil {
+			log.Printf("Failed to analyse %s: %v", filePath, err)
+		}
+		entry.STL = analysis
+	}
+	if (entry.AssetType == "stp" || entry.AssetType == "step") && entry.STEP == nil && entry.Validation.Valid {
+		metadata, err := parseSTEPFile(filePath)
+		if err != nil {
+			log.Printf("Failed to parse %s: %v", filePath, err)
+		}
+		entry.STEP = metadata
+	}
+	if (entry.AssetType == "jpg" || entry.AssetType == "png") && entry.ImageHash == nil && entry.Validation.Valid {
+		fingerprint, err := fingerprintImageFile(filePath)
+		if err != nil {
+			log.Printf("Failed to fingerprint %s: %v", filePath, err)
+		}
+		entry.ImageHash = fingerprint
+	}
+	if archiveWalkers[entry.AssetType] != nil && entry.Contents == nil && entry.Validation.Valid {
+		members, err := listArchive(filePath, entry.AssetType)
+		if err != nil {
+			log.Printf("Failed to list %s: %v", filePath, err)
+		}
+		entry.Contents = members
+	}
+	if languageTaggedTypes[entry.AssetType] && entry.Languages == nil && entry.Validation.Valid {
+		entry.Languages = detectEntryLanguages(entry)
+	}
+	return entry
+}
+
+// refreshAlias marks an image that was replaced by a near-duplicate as seen again, so it is not downloaded anew.
+// It reports whether the URL belongs to such an alias.
+func (archive *archiveManifest) refreshAlias(sourceURL string, contexts []linkContext) bool {
+	now := time.Now().UTC().Truncate(time.Second)
+	if now.Before(archive.LastRun) {
+		now = archive.LastRun
+	}
+	for _, entry := range archive.Entries {
+		if entry.AliasOf == "" || entry.URL != sourceURL {
+			continue
+		}
+		entry.LastSeen = now
+		for _, context := range contexts {
+			entry.Contexts = appendLinkContext(entry.Contexts, context)
+		}
+		return true
+	}
+	return false
+}
+
+// hashFile returns the size and hex SHA-256 of a file.
+func hashFile(filePath string) (int64, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// archiveFilesOfType returns the slash paths of every file of an asset type: the manifest entries
+// that are still on disk plus any file in the type folder the manifest does not know yet.
+func archiveFilesOfType(archive *archiveManifest, assetName string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for key, entry := range archive.Entries {
+		if entry.AssetType == assetName && fileExists(filepath.FromSlash(key)) {
+			seen[key] = true
+			paths = append(paths, key)
+		}
+	}
+	for index := range assetTypes {
+		asset := &assetTypes[index]
+		if asset.Name != assetName {
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(asset.OutputDir, "*"))
+		for _, match := range matches {
+			key := filepath.ToSlash(filepath.Clean(match))
+			if !seen[key] && assetTypeByExtension(filepath.Ext(key)) == asset {
+				seen[key] = true
+				paths = append(paths, key)
+			}
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// archiveFilesOnDisk returns the slash paths of every file under the type folders, including the folders
+// archives were extracted into. Links into the object store count as the files they point to;
+// half-written temporary files are skipped.
+func archiveFilesOnDisk() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, asset := range assetTypes {
+		filepath.WalkDir(asset.OutputDir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // A missing type folder has no files
+			}
+			name := entry.Name()
+			if entry.IsDir() {
+				if strings.HasSuffix(name, extractTempSuffix) || strings.HasPrefix(name, storageStagingPrefix) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, extractTempSuffix) {
+				return nil
+			}
+			if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			key := filepath.ToSlash(filepath.Clean(filePath))
+			if !seen[key] {
+				seen[key] = true
+				paths = append(paths, key)
+			}
+			return nil
+		})
+	}
+	slices.Sort(paths)
+	return paths
+}
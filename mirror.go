@@ -0,0 +1,162 @@
+package main // Define the main package
+
+import (
+	"encoding/json" // Provides decoding of the destination's manifest
+	"errors"        // Provides error values
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output
+	"io/fs"         // Provides fs.ErrNotExist
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice sorting helpers
+)
+
+// runMirror implements the mirror command: copy the archived files, previews, catalog and manifest
+// into a storage backend. Directories and buckets are updated incrementally; tarballs are written whole.
+func runMirror(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("mirror", flag.ExitOnError)
+	endpoint := flags.String("endpoint", "", "S3 endpoint URL, e.g. http://localhost:9000 for MinIO (default AWS_ENDPOINT_URL or AWS)")
+	region := flags.String("region", "", "S3 signing region (default AWS_REGION or us-east-1)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mirror [-endpoint URL] [-region name] <directory | snapshot.tar | snapshot.tar.zst | s3://bucket/prefix>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	destination, err := openStorage(flags.Arg(0), *endpoint, *region)
+	if err != nil {
+		log.Printf("Failed to open %s: %v", flags.Arg(0), err)
+		return 2
+	}
+	if mirrorArchive(destination, loadManifest(manifestPath)) {
+		return 0
+	}
+	return 1
+}
+
+// mirrorArchive puts every file of the archive into the storage and commits it, manifest last.
+// Files the destination's own manifest already lists with the same hash and size are skipped,
+// except for tarballs, which hold exactly what is put into them.
+func mirrorArchive(destination archiveStorage, archive *archiveManifest) bool {
+	_, snapshot := destination.(*tarStorage)
+	var mirrored *archiveManifest
+	if !snapshot {
+		mirrored = readStoredManifest(destination)
+	}
+
+	// The archived files and their previews, then the catalog
+	var names []string
+	for _, key := range slices.Sorted(maps.Keys(archive.Entries)) {
+		entry := archive.Entries[key]
+		if entry.AliasOf != "" {
+			continue
+		}
+		names = append(names, key)
+		if entry.Preview != "" {
+			names = append(names, entry.Preview)
+		}
+	}
+	names = append(names, catalogPath)
+	catalogPages, _ := filepath.Glob(filepath.Join(catalogPagesDir, "*.md"))
+	for _, page := range catalogPages {
+		names = append(names, filepath.ToSlash(page))
+	}
+
+	wanted := map[string]bool{manifestPath: true}
+	put, skipped := 0, 0
+	for _, name := range names {
+		if wanted[name] {
+			continue // e.g. a preview shared by identical models
+		}
+		wanted[name] = true
+		if !snapshot && unchangedInStorage(destination, mirrored, archive, name) {
+			skipped++
+			continue
+		}
+		if err := putLocalFile(destination, name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // Listed in the manifest but no longer on disk
+			}
+			log.Printf("Failed to store %s: %v", name, err)
+			destination.abort()
+			return false
+		}
+		put++
+		log.Printf("Stored %s", name)
+	}
+	// The manifest goes last, so it never lists files the destination does not have yet
+	if err := putLocalFile(destination, manifestPath); err != nil {
+		log.Printf("Failed to store %s: %v", manifestPath, err)
+		destination.abort()
+		return false
+	}
+	if err := destination.commit(); err != nil {
+		log.Printf("Failed to commit the mirror: %v", err)
+		destination.abort()
+		return false
+	}
+
+	// Report what the destination holds beyond the archive; mirrors never delete
+	extra := 0
+	if stored, err := destination.list(""); err == nil {
+		for _, file := range stored {
+			if !wanted[file.Name] {
+				extra++
+			}
+		}
+	}
+	log.Printf("Mirrored %d files (%d unchanged); %d files at the destination are no longer in the archive", put+1, skipped, extra)
+	return true
+}
+
+// readStoredManifest returns the manifest last mirrored into the storage, or nil if it has none.
+func readStoredManifest(destination archiveStorage) *archiveManifest {
+	reader, err := destination.open(manifestPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to read the mirrored manifest: %v", err)
+		}
+		return nil
+	}
+	defer reader.Close()
+	stored := &archiveManifest{}
+	if err := json.NewDecoder(reader).Decode(stored); err != nil {
+		log.Printf("Failed to parse the mirrored manifest: %v", err)
+		return nil
+	}
+	return stored
+}
+
+// unchangedInStorage reports whether the mirrored manifest lists a file with the same hash as the local
+// manifest and the storage holds it at the same size. Files outside the manifest are always copied.
+func unchangedInStorage(destination archiveStorage, mirrored, archive *archiveManifest, name string) bool {
+	if mirrored == nil {
+		return false
+	}
+	local, remote := archive.Entries[name], mirrored.Entries[name]
+	if local == nil || remote == nil || local.SHA256 == "" || local.SHA256 != remote.SHA256 {
+		return false
+	}
+	stored, err := destination.stat(name)
+	return err == nil && stored.Size == local.Size
+}
+
+// putLocalFile puts the file at the slash path into the storage under the same name.
+func putLocalFile(destination archiveStorage, name string) error {
+	file, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return destination.put(name, file, info.Size(), info.ModTime())
+}
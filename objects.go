@@ -0,0 +1,141 @@
+package main // Define the main package
+
+import (
+	"io"            // Provides basic interfaces to I/O primitives
+	"log"           // Provides logging functions
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+)
+
+// objectsDir is the content-addressed store: every distinct file is kept once as objects/sha256/<hex digest>.
+const objectsDir = "objects/sha256/"
+
+// objectPath returns where the contents with the given hash are stored.
+func objectPath(sum string) string {
+	return objectsDir + sum
+}
+
+// storeObjects moves every archived file into the content-addressed store and replaces it with a link,
+// so the type folders keep their familiar names while identical files share one copy. Files already
+// linked to their object are left alone, and objects no manifest entry refers to any more are removed.
+func storeObjects(archive *archiveManifest) bool {
+	if err := os.MkdirAll(objectsDir, 0o755); err != nil {
+		log.Printf("Failed to create %s: %v", objectsDir, err)
+		return false
+	}
+	referenced := make(map[string]bool)
+	stored, deduplicated := 0, 0
+	for _, entry := range archive.Entries {
+		if entry.SHA256 == "" || entry.AliasOf != "" {
+			continue
+		}
+		referenced[entry.SHA256] = true
+		filePath := filepath.FromSlash(entry.Path)
+		info, err := os.Lstat(filePath)
+		if err != nil || !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			continue // Missing on disk (e.g. removed by hand); the object, if any, is kept
+		}
+		object := filepath.FromSlash(objectPath(entry.SHA256))
+		if isLinkedToObject(filePath, object) {
+			continue
+		}
+
+		// Hash the bytes again so a file edited since it was recorded never lands under the wrong name
+		_, sum, err := hashFile(filePath)
+		if err != nil {
+			log.Printf("Failed to hash %s: %v", filePath, err)
+			continue
+		}
+		if sum != entry.SHA256 {
+			log.Printf("Skipping %s: contents changed since it was recorded (sha256 %s, manifest %s)", entry.Path, sum, entry.SHA256)
+			continue
+		}
+
+		// Move the bytes into the store, or drop them if an identical file is already there
+		if fileExists(object) {
+			deduplicated++
+		} else {
+			// A symlink pointing elsewhere is copied through; a regular file is simply moved
+			if info.Mode().IsRegular() {
+				err = os.Rename(filePath, object)
+			} else {
+				err = copyFile(filePath, object)
+			}
+			if err != nil {
+				log.Printf("Failed to move %s into %s: %v", filePath, objectsDir, err)
+				continue
+			}
+			// Objects never change; writing through a link by mistake would corrupt every name sharing it
+			os.Chmod(object, 0o444)
+			stored++
+		}
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove %s: %v", filePath, err)
+			continue
+		}
+		if !linkArchiveFile(object, filePath) {
+			// Put a copy back so the file is never lost from the type folder
+			if err := copyFile(object, filePath); err != nil {
+				log.Printf("Failed to restore %s from %s: %v", filePath, object, err)
+			}
+		}
+	}
+
+	// Remove objects of contents that are no longer archived
+	files, err := os.ReadDir(objectsDir)
+	if err != nil {
+		log.Printf("Failed to list %s: %v", objectsDir, err)
+		return false
+	}
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() || referenced[file.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(objectsDir, file.Name())); err != nil {
+			log.Printf("Failed to remove unreferenced object %s: %v", file.Name(), err)
+			continue
+		}
+		removed++
+	}
+	log.Printf("Stored %d new objects in %s, replaced %d duplicates with links, removed %d unreferenced objects",
+		stored, objectsDir, deduplicated, removed)
+	return true
+}
+
+// isLinkedToObject reports whether filePath already resolves to the object, as a symlink or a hardlink.
+func isLinkedToObject(filePath, object string) bool {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	objectInfo, err := os.Stat(object)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fileInfo, objectInfo)
+}
+
+// copyFile copies the contents of source to a new file at destination through a temporary file.
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	temporaryFile := destination + ".tmp"
+	out, err := os.Create(temporaryFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(temporaryFile)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(temporaryFile)
+		return err
+	}
+	return os.Rename(temporaryFile, destination)
+}
@@ -0,0 +1,147 @@
+package main // Define the main package
+
+import (
+	"log"     // Provides logging functions
+	"net/url" // Provides URL parsing and encoding
+	"strconv" // Provides page number formatting
+	"strings" // Provides string manipulation functions
+
+	"golang.org/x/net/html" // Provides HTML parsing functions
+)
+
+// defaultMaxPages caps how many pages of one listing are followed.
+const defaultMaxPages = 50
+
+// pageNumberPlaceholder is replaced by the page number in a pagination pattern.
+const pageNumberPlaceholder = "{page}"
+
+// nextPageTexts are the link texts pagination widgets use for the following page.
+var nextPageTexts = map[string]bool{"next": true, "next page": true, "›": true, "»": true, "→": true, ">": true, "older": true, "more": true}
+
+// crawlPages fetches every page and the pages that follow it, and returns the asset links found on all of them.
+// A listing is followed through its rel="next" or pagination links, or through the numbered pattern when the
+// page has none, until a page adds no new links, the site disallows the next URL or maxPages is reached.
+func crawlPages(pages []string, pattern string, maxPages int) []discoveredLink {
+	var discoveredLinks []discoveredLink
+	visited := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, firstPage := range pages {
+		pageURL := firstPage
+		for pageNumber := 1; pageURL != "" && !visited[pageURL]; pageNumber++ {
+			if pageNumber > maxPages {
+				log.Printf("Stopped following %s after %d pages (-max-pages)", firstPage, maxPages)
+				break
+			}
+			if !isAllowedURL(pageURL) {
+				log.Printf("Skipping page %s: host is not allowed for site %s", pageURL, currentSite.name())
+				break
+			}
+			visited[pageURL] = true
+			pageData := getDataFromURL(pageURL)
+			if pageData == "" {
+				break
+			}
+			// Let the site adapter find the asset links, resolved against this page.
+			links := currentSite.extractLinks(pageData, pageURL)
+			newLinks := 0
+			for _, link := range links {
+				if !known[link.URL] {
+					known[link.URL] = true
+					newLinks++
+				}
+			}
+			discoveredLinks = append(discoveredLinks, links...)
+			if pageNumber > 1 && newLinks == 0 {
+				log.Printf("No new links on %s; stopped following %s", pageURL, firstPage)
+				break
+			}
+			// The page's own pagination wins; the pattern covers listings without any
+			pageURL = currentSite.nextPage(pageData, pageURL)
+			if pageURL == "" && pattern != "" {
+				pageURL = pagePatternURL(firstPage, pattern, pageNumber+1)
+			}
+		}
+	}
+	return discoveredLinks
+}
+
+// pagePatternURL fills the page number into the pattern and resolves it against the first page, so
+// "?page={page}" numbers the first page's query and "/collections/all/page/{page}" replaces its path.
+func pagePatternURL(firstPage, pattern string, pageNumber int) string {
+	baseURL, err := url.Parse(firstPage)
+	if err != nil {
+		return ""
+	}
+	return resolveLinkURL(baseURL, strings.ReplaceAll(pattern, pageNumberPlaceholder, strconv.Itoa(pageNumber)))
+}
+
+// findNextPageURL returns the URL of the page after this one, or "". A <link> or <a> with rel="next" wins;
+// otherwise a link marked as "next" by its class or aria-label, or labelled "Next" inside a pagination
+// widget, is used.
+func findNextPageURL(htmlContent, pageURL string) string {
+	// Try parsing the HTML content into a document tree
+	document, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+
+	// Relative links resolve against the page URL or its <base href>
+	baseURL := documentBaseURL(document, pageURL)
+
+	relNext, markupNext := "", ""
+	var traverse func(node *html.Node, inPagination bool)
+	traverse = func(node *html.Node, inPagination bool) {
+		if node.Type == html.ElementNode {
+			class := strings.ToLower(attributeValue(node, "class"))
+			label := strings.ToLower(attributeValue(node, "aria-label"))
+			if strings.Contains(class, "paginat") || strings.Contains(label, "pagination") {
+				inPagination = true
+			}
+			if node.Data == "a" || node.Data == "link" {
+				link := resolveLinkURL(baseURL, attributeValue(node, "href"))
+				switch {
+				case link == "" || link == pageURL:
+				case hasToken(attributeValue(node, "rel"), "next"):
+					if relNext == "" {
+						relNext = link
+					}
+				case node.Data == "a" && markupNext == "" && isNextPageLink(node, class, label, inPagination):
+					markupNext = link
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			traverse(child, inPagination)
+		}
+	}
+	traverse(document, false)
+	if relNext != "" {
+		return relNext
+	}
+	return markupNext
+}
+
+// isNextPageLink reports whether an <a> is marked up as the link to the next page.
+func isNextPageLink(node *html.Node, class, label string, inPagination bool) bool {
+	for _, token := range strings.FieldsFunc(class, func(character rune) bool {
+		return character == ' ' || character == '-' || character == '_'
+	}) {
+		if token == "next" {
+			return true
+		}
+	}
+	if strings.HasPrefix(label, "next") {
+		return true
+	}
+	return inPagination && nextPageTexts[strings.ToLower(nodeText(node))]
+}
+
+// hasToken reports whether a space-separated attribute value such as rel contains the token.
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(strings.ToLower(value)) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
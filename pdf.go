@@ -0,0 +1,1032 @@
+package main // Define the main package
+
+import (
+	"bytes"            // Provides bytes support
+	"compress/flate"   // Provides raw deflate for streams without a zlib header
+	"compress/zlib"    // Provides FlateDecode (zlib) decompression
+	"encoding/ascii85" // Provides ASCII85Decode
+	"encoding/hex"     // Provides ASCIIHexDecode
+	"errors"           // Provides error values
+	"fmt"              // Provides formatted errors
+	"io"               // Provides basic interfaces to I/O primitives
+	"regexp"           // Provides regex support functions.
+	"strconv"          // Provides number parsing
+)
+
+// pdfName is a PDF name object such as /Type (stored without the slash).
+type pdfName string
+
+// pdfKeyword is a bare PDF keyword such as obj, stream or a content stream operator.
+type pdfKeyword string
+
+// pdfString is the raw bytes of a PDF literal or hex string.
+type pdfString string
+
+// pdfDict is a PDF dictionary.
+type pdfDict map[string]any
+
+// pdfRef is an indirect object reference ("12 0 R").
+type pdfRef struct {
+	Number     int
+	Generation int
+}
+
+// pdfStream is a stream object: its dictionary and the raw (still encoded) bytes.
+type pdfStream struct {
+	Dict pdfDict
+	Raw  []byte
+}
+
+// pdfMaxDecodedSize caps decompressed streams so a hostile file cannot exhaust memory.
+const pdfMaxDecodedSize = 64 << 20
+
+// pdfObjectHeaderRegex finds "12 0 obj" headers when the cross-reference table is unusable.
+var pdfObjectHeaderRegex = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfDocument is a parsed PDF file with lazily loaded objects.
+type pdfDocument struct {
+	data          []byte         // Whole file
+	offsets       map[int]int    // Object number → byte offset of "n g obj"
+	compressed    map[int][2]int // Object number → (object stream number, index)
+	trailer       pdfDict        // Merged trailer dictionary
+	cache         map[int]any    // Objects already parsed
+	xrefRecovered bool           // True when offsets came from scanning instead of the xref table
+}
+
+// pdfLexer reads PDF tokens and objects from a byte slice.
+type pdfLexer struct {
+	data     []byte
+	position int
+	document *pdfDocument // Used to resolve indirect /Length values; may be nil
+}
+
+// isPDFWhitespace reports whether the byte is PDF whitespace.
+func isPDFWhitespace(character byte) bool {
+	return character == ' ' || character == '\n' || character == '\r' || character == '\t' || character == '\f' || character == 0
+}
+
+// isPDFDelimiter reports whether the byte ends a name, number or keyword.
+func isPDFDelimiter(character byte) bool {
+	switch character {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isPDFWhitespace(character)
+}
+
+// skipSpace moves past whitespace and comments.
+func (lexer *pdfLexer) skipSpace() {
+	for lexer.position < len(lexer.data) {
+		character := lexer.data[lexer.position]
+		if isPDFWhitespace(character) {
+			lexer.position++
+			continue
+		}
+		if character == '%' {
+			for lexer.position < len(lexer.data) && lexer.data[lexer.position] != '\n' && lexer.data[lexer.position] != '\r' {
+				lexer.position++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// readWord reads a run of regular (non-delimiter) characters.
+func (lexer *pdfLexer) readWord() string {
+	start := lexer.position
+	for lexer.position < len(lexer.data) && !isPDFDelimiter(lexer.data[lexer.position]) {
+		lexer.position++
+	}
+	return string(lexer.data[start:lexer.position])
+}
+
+// readObject parses the next object. Numbers followed by "gen R" become references.
+func (lexer *pdfLexer) readObject() (any, error) {
+	lexer.skipSpace()
+	if lexer.position >= len(lexer.data) {
+		return nil, io.EOF
+	}
+	character := lexer.data[lexer.position]
+	switch {
+	case character == '/':
+		lexer.position++
+		return pdfName(decodePDFName(lexer.readWord())), nil
+	case character == '(':
+		return lexer.readLiteralString()
+	case character == '<' && lexer.position+1 < len(lexer.data) && lexer.data[lexer.position+1] == '<':
+		lexer.position += 2
+		return lexer.readDictOrStream()
+	case character == '<':
+		return lexer.readHexString()
+	case character == '[':
+		lexer.position++
+		var array []any
+		for {
+			lexer.skipSpace()
+			if lexer.position >= len(lexer.data) {
+				return array, errors.New("unterminated array")
+			}
+			if lexer.data[lexer.position] == ']' {
+				lexer.position++
+				return array, nil
+			}
+			item, err := lexer.readObject()
+			if err != nil {
+				return array, err
+			}
+			array = append(array, item)
+		}
+	case character == ']' || character == '>' || character == ')' || character == '{' || character == '}':
+		lexer.position++
+		return pdfKeyword(string(character)), nil
+	case character == '+' || character == '-' || character == '.' || (character >= '0' && character <= '9'):
+		return lexer.readNumberOrRef()
+	}
+	word := lexer.readWord()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if word == "" {
+		lexer.position++ // Skip stray bytes rather than looping forever
+	}
+	return pdfKeyword(word), nil
+}
+
+// readNumberOrRef reads a number, turning "n g R" into a reference.
+func (lexer *pdfLexer) readNumberOrRef() (any, error) {
+	word := lexer.readWord()
+	number, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfKeyword(word), nil // Malformed numbers are treated as keywords
+	}
+	// Look ahead for "gen R" after a non-negative integer
+	if isPDFInteger(word) {
+		saved := lexer.position
+		lexer.skipSpace()
+		generationStart := lexer.position
+		generation := lexer.readWord()
+		if generationStart != lexer.position && isPDFInteger(generation) {
+			lexer.skipSpace()
+			if lexer.position < len(lexer.data) && lexer.data[lexer.position] == 'R' &&
+				(lexer.position+1 >= len(lexer.data) || isPDFDelimiter(lexer.data[lexer.position+1])) {
+				lexer.position++
+				generationNumber, _ := strconv.Atoi(generation)
+				return pdfRef{Number: int(number), Generation: generationNumber}, nil
+			}
+		}
+		lexer.position = saved
+	}
+	return number, nil
+}
+
+// isPDFInteger reports whether the word is an unsigned integer.
+func isPDFInteger(word string) bool {
+	if word == "" {
+		return false
+	}
+	for index := 0; index < len(word); index++ {
+		if word[index] < '0' || word[index] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePDFName resolves #xx escapes in a name.
+func decodePDFName(name string) string {
+	if !bytes.ContainsRune([]byte(name), '#') {
+		return name
+	}
+	var decoded []byte
+	for index := 0; index < len(name); index++ {
+		if name[index] == '#' && index+2 < len(name) {
+			if value, err := strconv.ParseUint(name[index+1:index+3], 16, 8); err == nil {
+				decoded = append(decoded, byte(value))
+				index += 2
+				continue
+			}
+		}
+		decoded = append(decoded, name[index])
+	}
+	return string(decoded)
+}
+
+// readLiteralString reads a (...) string with nested parentheses and escapes.
+func (lexer *pdfLexer) readLiteralString() (any, error) {
+	lexer.position++ // Skip "("
+	var decoded []byte
+	depth := 1
+	for lexer.position < len(lexer.data) {
+		character := lexer.data[lexer.position]
+		lexer.position++
+		switch character {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(decoded), nil
+			}
+		case '\\':
+			if lexer.position >= len(lexer.data) {
+				break
+			}
+			escaped := lexer.data[lexer.position]
+			lexer.position++
+			switch escaped {
+			case 'n':
+				decoded = append(decoded, '\n')
+			case 'r':
+				decoded = append(decoded, '\r')
+			case 't':
+				decoded = append(decoded, '\t')
+			case 'b':
+				decoded = append(decoded, '\b')
+			case 'f':
+				decoded = append(decoded, '\f')
+			case '\r':
+				// Line continuation (CR or CRLF)
+				if lexer.position < len(lexer.data) && lexer.data[lexer.position] == '\n' {
+					lexer.position++
+				}
+			case '\n':
+				// Line continuation
+			default:
+				if escaped >= '0' && escaped <= '7' {
+					// Up to three octal digits
+					value := int(escaped - '0')
+					for digits := 1; digits < 3 && lexer.position < len(lexer.data); digits++ {
+						next := lexer.data[lexer.position]
+						if next < '0' || next > '7' {
+							break
+						}
+						value = value*8 + int(next-'0')
+						lexer.position++
+					}
+					decoded = append(decoded, byte(value))
+				} else {
+					decoded = append(decoded, escaped)
+				}
+			}
+			continue
+		}
+		decoded = append(decoded, character)
+	}
+	return pdfString(decoded), errors.New("unterminated string")
+}
+
+// readHexString reads a <...> hex string; an odd final digit is padded with 0.
+func (lexer *pdfLexer) readHexString() (any, error) {
+	lexer.position++ // Skip "<"
+	var digits []byte
+	for lexer.position < len(lexer.data) && lexer.data[lexer.position] != '>' {
+		if character := lexer.data[lexer.position]; !isPDFWhitespace(character) {
+			digits = append(digits, character)
+		}
+		lexer.position++
+	}
+	if lexer.position < len(lexer.data) {
+		lexer.position++ // Skip ">"
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	decoded := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(decoded, digits); err != nil {
+		return pdfString(""), err
+	}
+	return pdfString(decoded), nil
+}
+
+// readDictOrStream reads the rest of a << ... >> dictionary and the stream that may follow it.
+func (lexer *pdfLexer) readDictOrStream() (any, error) {
+	dict := pdfDict{}
+	for {
+		lexer.skipSpace()
+		if lexer.position+1 < len(lexer.data) && lexer.data[lexer.position] == '>' && lexer.data[lexer.position+1] == '>' {
+			lexer.position += 2
+			break
+		}
+		key, err := lexer.readObject()
+		if err != nil {
+			return dict, err
+		}
+		name, isName := key.(pdfName)
+		if !isName {
+			continue // Skip junk until the next name
+		}
+		value, err := lexer.readObject()
+		if err != nil {
+			return dict, err
+		}
+		dict[string(name)] = value
+	}
+
+	// Check for a stream body after the dictionary
+	saved := lexer.position
+	lexer.skipSpace()
+	if !bytes.HasPrefix(lexer.data[lexer.position:], []byte("stream")) {
+		lexer.position = saved
+		return dict, nil
+	}
+	lexer.position += len("stream")
+	// The keyword is followed by CRLF or LF (some writers use a lone CR)
+	if lexer.position < len(lexer.data) && lexer.data[lexer.position] == '\r' {
+		lexer.position++
+	}
+	if lexer.position < len(lexer.data) && lexer.data[lexer.position] == '\n' {
+		lexer.position++
+	}
+	start := lexer.position
+
+	// Use /Length when it is sane, otherwise search for endstream
+	length := -1
+	lengthValue := dict["Length"]
+	if lexer.document != nil {
+		lengthValue = lexer.document.resolve(lengthValue)
+	}
+	if number, ok := lengthValue.(float64); ok && number >= 0 && start+int(number) <= len(lexer.data) {
+		end := start + int(number)
+		rest := lexer.data[end:min(len(lexer.data), end+32)]
+		if bytes.Contains(rest, []byte("endstream")) {
+			length = int(number)
+		}
+	}
+	if length < 0 {
+		end := bytes.Index(lexer.data[start:], []byte("endstream"))
+		if end < 0 {
+			return &pdfStream{Dict: dict, Raw: lexer.data[start:]}, errors.New("missing endstream")
+		}
+		length = end
+		// Trim the EOL that precedes endstream
+		for length > 0 && (lexer.data[start+length-1] == '\n' || lexer.data[start+length-1] == '\r') {
+			length--
+		}
+	}
+	lexer.position = start + length
+	lexer.skipSpace()
+	if bytes.HasPrefix(lexer.data[lexer.position:], []byte("endstream")) {
+		lexer.position += len("endstream")
+	}
+	return &pdfStream{Dict: dict, Raw: lexer.data[start : start+length]}, nil
+}
+
+// openPDF parses the cross-reference data of a PDF file. When the xref table or stream is broken,
+// object offsets are recovered by scanning the file for "n g obj" headers.
+func openPDF(data []byte) (*pdfDocument, error) {
+	document := &pdfDocument{
+		data:       data,
+		offsets:    make(map[int]int),
+		compressed: make(map[int][2]int),
+		trailer:    pdfDict{},
+		cache:      make(map[int]any),
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\r\n\t "), []byte("%PDF-")) && !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("missing %PDF header")
+	}
+	if err := document.readCrossReferences(); err != nil {
+		// Fall back to scanning the whole file
+		document.offsets = make(map[int]int)
+		document.compressed = make(map[int][2]int)
+		document.trailer = pdfDict{}
+		if scanErr := document.recoverCrossReferences(); scanErr != nil {
+			return nil, fmt.Errorf("%v; recovery failed: %v", err, scanErr)
+		}
+		document.xrefRecovered = true
+	}
+	return document, nil
+}
+
+// readCrossReferences follows startxref and every /Prev section, newest first.
+func (document *pdfDocument) readCrossReferences() error {
+	startIndex := bytes.LastIndex(document.data, []byte("startxref"))
+	if startIndex < 0 {
+		return errors.New("missing startxref")
+	}
+	lexer := &pdfLexer{data: document.data, position: startIndex + len("startxref")}
+	offsetValue, err := lexer.readObject()
+	offset, ok := offsetValue.(float64)
+	if err != nil || !ok {
+		return errors.New("invalid startxref offset")
+	}
+
+	visited := make(map[int]bool)
+	for next := int(offset); !visited[next]; { // Loops in /Prev chains end the walk
+		visited[next] = true
+		section, err := document.readCrossReferenceSection(next)
+		if err != nil {
+			return err
+		}
+		// Older trailers only fill in keys the newer ones lack
+		for key, value := range section {
+			if _, found := document.trailer[key]; !found && key != "Prev" && key != "XRefStm" {
+				document.trailer[key] = value
+			}
+		}
+		// Hybrid files keep the compressed entries in a separate xref stream
+		if hybrid, ok := section["XRefStm"].(float64); ok && !visited[int(hybrid)] {
+			visited[int(hybrid)] = true
+			if _, err := document.readCrossReferenceSection(int(hybrid)); err != nil {
+				return err
+			}
+		}
+		previous, ok := section["Prev"].(float64)
+		if !ok || previous == 0 {
+			break
+		}
+		next = int(previous)
+	}
+	if _, found := document.trailer["Root"]; !found {
+		return errors.New("trailer has no /Root")
+	}
+	return nil
+}
+
+// readCrossReferenceSection reads a classic xref table or an xref stream at the offset and returns its trailer.
+func (document *pdfDocument) readCrossReferenceSection(offset int) (pdfDict, error) {
+	if !document.inFile(offset) {
+		return nil, fmt.Errorf("xref offset %d outside the file", offset)
+	}
+	lexer := &pdfLexer{data: document.data, position: offset, document: document}
+	lexer.skipSpace()
+	if !bytes.HasPrefix(document.data[lexer.position:], []byte("xref")) {
+		return document.readCrossReferenceStream(lexer)
+	}
+	lexer.position += len("xref")
+
+	// Subsections: "start count" followed by count entries "offset generation n|f"
+	for {
+		first, err := lexer.readObject()
+		if err != nil {
+			return nil, fmt.Errorf("truncated xref table: %v", err)
+		}
+		if keyword, isKeyword := first.(pdfKeyword); isKeyword && keyword == "trailer" {
+			break
+		}
+		countValue, err := lexer.readObject()
+		start, startOK := first.(float64)
+		count, countOK := countValue.(float64)
+		if err != nil || !startOK || !countOK || count < 0 {
+			return nil, errors.New("malformed xref subsection header")
+		}
+		for index := 0; index < int(count); index++ {
+			entryOffset, err1 := lexer.readObject()
+			_, err2 := lexer.readObject()
+			kind, err3 := lexer.readObject()
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, errors.New("truncated xref entry")
+			}
+			number := int(start) + index
+			value, ok := entryOffset.(float64)
+			if !ok {
+				return nil, errors.New("malformed xref entry")
+			}
+			if kind != pdfKeyword("n") || document.known(number) {
+				continue
+			}
+			if !document.inFile(int(value)) {
+				return nil, fmt.Errorf("object %d offset %d outside the file", number, int(value))
+			}
+			document.offsets[number] = int(value)
+		}
+	}
+	trailerValue, err := lexer.readObject()
+	trailer, ok := trailerValue.(pdfDict)
+	if err != nil || !ok {
+		return nil, errors.New("malformed trailer dictionary")
+	}
+	return trailer, nil
+}
+
+// inFile reports whether the byte offset lies inside the file. Offsets come from the file itself,
+// so every one is checked before the data is sliced at it.
+func (document *pdfDocument) inFile(offset int) bool {
+	return offset >= 0 && offset < len(document.data)
+}
+
+// known reports whether a newer xref section already located the object.
+func (document *pdfDocument) known(number int) bool {
+	if _, found := document.offsets[number]; found {
+		return true
+	}
+	_, found := document.compressed[number]
+	return found
+}
+
+// readCrossReferenceStream reads a PDF 1.5 xref stream ("n g obj << /Type /XRef ... >> stream").
+func (document *pdfDocument) readCrossReferenceStream(lexer *pdfLexer) (pdfDict, error) {
+	object, err := lexer.readIndirectObject()
+	if err != nil {
+		return nil, fmt.Errorf("xref stream: %v", err)
+	}
+	stream, ok := object.(*pdfStream)
+	if !ok || stream.Dict["Type"] != pdfName("XRef") {
+		return nil, errors.New("xref offset does not point at an xref table or stream")
+	}
+	decoded, err := document.decodeStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("xref stream: %v", err)
+	}
+
+	// /W gives the byte width of the three fields of every entry
+	widthValues, _ := stream.Dict["W"].([]any)
+	if len(widthValues) != 3 {
+		return nil, errors.New("xref stream has no valid /W")
+	}
+	var widths [3]int
+	for index, value := range widthValues {
+		width, _ := value.(float64)
+		if width < 0 || width > 8 {
+			return nil, errors.New("xref stream has an invalid /W")
+		}
+		widths[index] = int(width)
+	}
+	entrySize := widths[0] + widths[1] + widths[2]
+	if entrySize == 0 {
+		return nil, errors.New("xref stream has zero-width entries")
+	}
+
+	// /Index lists (start, count) pairs; the default covers 0..Size-1
+	size, _ := stream.Dict["Size"].(float64)
+	index := []any{0.0, size}
+	if indexValues, ok := stream.Dict["Index"].([]any); ok && len(indexValues)%2 == 0 {
+		index = indexValues
+	}
+	position := 0
+	for pair := 0; pair < len(index); pair += 2 {
+		start, _ := index[pair].(float64)
+		count, _ := index[pair+1].(float64)
+		for entry := 0; entry < int(count); entry++ {
+			if position+entrySize > len(decoded) {
+				return stream.Dict, nil // Truncated streams keep what was read
+			}
+			fields := [3]int{1, 0, 0} // Type defaults to 1 when its width is 0
+			for field := 0; field < 3; field++ {
+				if widths[field] == 0 {
+					continue
+				}
+				value := 0
+				for byteIndex := 0; byteIndex < widths[field]; byteIndex++ {
+					value = value<<8 | int(decoded[position])
+					position++
+				}
+				fields[field] = value
+			}
+			number := int(start) + entry
+			if number < 0 || document.known(number) {
+				continue
+			}
+			switch fields[0] {
+			case 1:
+				if !document.inFile(fields[1]) {
+					return nil, fmt.Errorf("object %d offset %d outside the file", number, fields[1])
+				}
+				document.offsets[number] = fields[1]
+			case 2:
+				document.compressed[number] = [2]int{fields[1], fields[2]}
+			}
+		}
+	}
+	return stream.Dict, nil
+}
+
+// recoverCrossReferences rebuilds object offsets by scanning for "n g obj" and finds the catalog.
+func (document *pdfDocument) recoverCrossReferences() error {
+	for _, match := range pdfObjectHeaderRegex.FindAllSubmatchIndex(document.data, -1) {
+		number, err := strconv.Atoi(string(document.data[match[2]:match[3]]))
+		if err != nil {
+			continue
+		}
+		document.offsets[number] = match[2] // Later definitions win, as in incremental updates
+	}
+	if len(document.offsets) == 0 {
+		return errors.New("no objects found")
+	}
+	// Register objects that live inside object streams; objects found directly in the file take precedence
+	for number := range document.offsets {
+		if stream, ok := document.object(number).(*pdfStream); !ok || stream.Dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		members, _, err := document.objectStreamHeader(number)
+		if err != nil {
+			continue
+		}
+		for entry, member := range members {
+			if _, found := document.offsets[member]; !found {
+				document.compressed[member] = [2]int{number, entry}
+			}
+		}
+	}
+
+	// Use the last trailer if any, otherwise the first catalog object
+	if trailerIndex := bytes.LastIndex(document.data, []byte("trailer")); trailerIndex >= 0 {
+		lexer := &pdfLexer{data: document.data, position: trailerIndex + len("trailer"), document: document}
+		if trailer, ok := mustObject(lexer.readObject()).(pdfDict); ok {
+			document.trailer = trailer
+		}
+	}
+	if _, found := document.trailer["Root"]; !found {
+		for number := range document.offsets {
+			if dict := document.dictOf(document.object(number)); dict != nil && dict["Type"] == pdfName("Catalog") {
+				document.trailer["Root"] = pdfRef{Number: number}
+				break
+			}
+		}
+	}
+	if _, found := document.trailer["Root"]; !found {
+		return errors.New("no document catalog found")
+	}
+	return nil
+}
+
+// mustObject drops the error of readObject for best-effort parsing.
+func mustObject(object any, _ error) any {
+	return object
+}
+
+// readIndirectObject reads "n g obj <object> endobj" at the lexer position.
+func (lexer *pdfLexer) readIndirectObject() (any, error) {
+	for _, expected := range []string{"number", "generation"} {
+		value, err := lexer.readObject()
+		if _, ok := value.(float64); err != nil || !ok {
+			return nil, fmt.Errorf("expected object %s", expected)
+		}
+	}
+	if keyword, err := lexer.readObject(); err != nil || keyword != pdfKeyword("obj") {
+		return nil, errors.New("expected obj keyword")
+	}
+	return lexer.readObject()
+}
+
+// object returns the object with the given number, or nil if it cannot be loaded.
+func (document *pdfDocument) object(number int) any {
+	if cached, found := document.cache[number]; found {
+		return cached
+	}
+	document.cache[number] = nil // Guards against reference cycles while loading
+	var loaded any
+	if offset, found := document.offsets[number]; found && document.inFile(offset) {
+		lexer := &pdfLexer{data: document.data, position: offset, document: document}
+		loaded, _ = lexer.readIndirectObject()
+	} else if location, found := document.compressed[number]; found {
+		loaded = document.objectFromStream(location[0], location[1])
+	}
+	document.cache[number] = loaded
+	return loaded
+}
+
+// objectStreamHeader decodes an object stream and returns its object numbers, offsets and data.
+func (document *pdfDocument) objectStreamHeader(streamNumber int) ([]int, []byte, error) {
+	stream, ok := document.object(streamNumber).(*pdfStream)
+	if !ok {
+		return nil, nil, errors.New("object stream not found")
+	}
+	decoded, err := document.decodeStream(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	count, _ := stream.Dict["N"].(float64)
+	lexer := &pdfLexer{data: decoded}
+	var numbers []int
+	for entry := 0; entry < int(count); entry++ {
+		number, isNumber := mustObject(lexer.readObject()).(float64)
+		_, err := lexer.readObject() // Offset, re-read by objectFromStream
+		if !isNumber || err != nil {
+			break // /N may claim more objects than the stream holds
+		}
+		numbers = append(numbers, int(number))
+	}
+	return numbers, decoded, nil
+}
+
+// objectFromStream parses the index-th object of an object stream.
+func (document *pdfDocument) objectFromStream(streamNumber, index int) any {
+	stream, ok := document.object(streamNumber).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	decoded, err := document.decodeStream(stream)
+	if err != nil {
+		return nil
+	}
+	first, _ := stream.Dict["First"].(float64)
+	lexer := &pdfLexer{data: decoded}
+	offset := -1
+	for entry := 0; entry <= index; entry++ {
+		lexer.readObject() // Object number
+		value, err := lexer.readObject()
+		number, isNumber := value.(float64)
+		if err != nil || !isNumber {
+			return nil // The index lies beyond the objects the stream holds
+		}
+		offset = int(number)
+	}
+	start := int(first) + offset
+	if first < 0 || offset < 0 || start < 0 || start >= len(decoded) {
+		return nil
+	}
+	objectLexer := &pdfLexer{data: decoded, position: start, document: document}
+	object, _ := objectLexer.readObject()
+	return object
+}
+
+// resolve follows indirect references until a direct object is reached.
+func (document *pdfDocument) resolve(value any) any {
+	for depth := 0; depth < 32; depth++ {
+		ref, isRef := value.(pdfRef)
+		if !isRef {
+			return value
+		}
+		value = document.object(ref.Number)
+	}
+	return nil
+}
+
+// dictOf returns the dictionary of a dictionary or stream object, or nil.
+func (document *pdfDocument) dictOf(value any) pdfDict {
+	switch typed := document.resolve(value).(type) {
+	case pdfDict:
+		return typed
+	case *pdfStream:
+		return typed.Dict
+	}
+	return nil
+}
+
+// decodeStream applies the stream's filters and returns the decoded bytes.
+func (document *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	filters := document.resolve(stream.Dict["Filter"])
+	parameters := document.resolve(stream.Dict["DecodeParms"])
+	filterList, parameterList := []any{filters}, []any{parameters}
+	if array, ok := filters.([]any); ok {
+		filterList = array
+		parameterList, _ = parameters.([]any)
+	}
+	data := stream.Raw
+	for index, filter := range filterList {
+		if filter == nil {
+			continue
+		}
+		var filterParameters pdfDict
+		if index < len(parameterList) {
+			filterParameters = document.dictOf(parameterList[index])
+		}
+		name, _ := document.resolve(filter).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflatePDF(data)
+			if err == nil {
+				data, err = applyPDFPredictor(data, filterParameters)
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodePDFHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodePDFASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflatePDF decompresses zlib data, falling back to raw deflate. Truncated data keeps what was inflated.
+func inflatePDF(data []byte) ([]byte, error) {
+	var reader io.Reader
+	if zlibReader, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		reader = zlibReader
+	} else {
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	decoded, err := io.ReadAll(io.LimitReader(reader, pdfMaxDecodedSize))
+	if err != nil && len(decoded) == 0 {
+		return nil, fmt.Errorf("FlateDecode: %v", err)
+	}
+	return decoded, nil
+}
+
+// applyPDFPredictor undoes PNG (10-15) and TIFF (2) predictors from /DecodeParms.
+func applyPDFPredictor(data []byte, parameters pdfDict) ([]byte, error) {
+	predictor, _ := parameters["Predictor"].(float64)
+	if predictor < 2 {
+		return data, nil
+	}
+	columns, colors, bitsPerComponent := 1.0, 1.0, 8.0
+	if value, ok := parameters["Columns"].(float64); ok {
+		columns = value
+	}
+	if value, ok := parameters["Colors"].(float64); ok {
+		colors = value
+	}
+	if value, ok := parameters["BitsPerComponent"].(float64); ok {
+		bitsPerComponent = value
+	}
+	bytesPerPixel := max(1, int(colors*bitsPerComponent+7)/8)
+	rowSize := int(columns*colors*bitsPerComponent+7) / 8
+	if rowSize <= 0 {
+		return nil, errors.New("invalid predictor columns")
+	}
+
+	if predictor == 2 {
+		// TIFF predictor 2 (8-bit components only)
+		output := bytes.Clone(data)
+		for row := 0; row+rowSize <= len(output); row += rowSize {
+			for index := bytesPerPixel; index < rowSize; index++ {
+				output[row+index] += output[row+index-bytesPerPixel]
+			}
+		}
+		return output, nil
+	}
+
+	// PNG predictors: every row starts with its own filter type byte
+	var output []byte
+	previous := make([]byte, rowSize)
+	for position := 0; position+1+rowSize <= len(data); position += 1 + rowSize {
+		filterType := data[position]
+		row := bytes.Clone(data[position+1 : position+1+rowSize])
+		for index := range row {
+			var left, upLeft byte
+			if index >= bytesPerPixel {
+				left = row[index-bytesPerPixel]
+				upLeft = previous[index-bytesPerPixel]
+			}
+			up := previous[index]
+			switch filterType {
+			case 1:
+				row[index] += left
+			case 2:
+				row[index] += up
+			case 3:
+				row[index] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[index] += paethPredictor(left, up, upLeft)
+			}
+		}
+		output = append(output, row...)
+		previous = row
+	}
+	return output, nil
+}
+
+// paethPredictor implements the PNG Paeth filter.
+func paethPredictor(left, up, upLeft byte) byte {
+	estimate := int(left) + int(up) - int(upLeft)
+	distanceLeft := abs(estimate - int(left))
+	distanceUp := abs(estimate - int(up))
+	distanceUpLeft := abs(estimate - int(upLeft))
+	if distanceLeft <= distanceUp && distanceLeft <= distanceUpLeft {
+		return left
+	}
+	if distanceUp <= distanceUpLeft {
+		return up
+	}
+	return upLeft
+}
+
+// abs returns the absolute value of an integer.
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// decodePDFHex decodes ASCIIHexDecode data up to the ">" end marker.
+func decodePDFHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, character := range data {
+		if character == '>' {
+			break
+		}
+		if !isPDFWhitespace(character) {
+			digits = append(digits, character)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	decoded := make([]byte, len(digits)/2)
+	_, err := hex.Decode(decoded, digits)
+	return decoded, err
+}
+
+// decodePDFASCII85 decodes ASCII85Decode data up to the "~>" end marker.
+func decodePDFASCII85(data []byte) ([]byte, error) {
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	decoded := make([]byte, 4*len(data)/5+4)
+	written, _, err := ascii85.Decode(decoded, data, true)
+	return decoded[:written], err
+}
+
+// pdfPage is one leaf of the page tree with its inherited resources.
+type pdfPage struct {
+	Dict      pdfDict
+	Resources pdfDict
+}
+
+// pages walks the page tree from the catalog and returns every page in order.
+func (document *pdfDocument) pages() ([]pdfPage, error) {
+	catalog := document.dictOf(document.trailer["Root"])
+	if catalog == nil {
+		return nil, errors.New("document catalog is missing")
+	}
+	root := document.dictOf(catalog["Pages"])
+	if root == nil {
+		return nil, errors.New("page tree root is missing")
+	}
+
+	var pages []pdfPage
+	var walk func(node pdfDict, resources pdfDict, depth int) error
+	walk = func(node pdfDict, resources pdfDict, depth int) error {
+		// Cyclic trees are caught by the depth limit
+		if depth > 64 {
+			return errors.New("page tree is too deep or cyclic")
+		}
+		// Resources are inherited from the nearest ancestor that has them
+		if own := document.dictOf(node["Resources"]); own != nil {
+			resources = own
+		}
+		kids, hasKids := document.resolve(node["Kids"]).([]any)
+		if node["Type"] == pdfName("Page") || (!hasKids && node["Type"] != pdfName("Pages")) {
+			pages = append(pages, pdfPage{Dict: node, Resources: resources})
+			return nil
+		}
+		for _, kid := range kids {
+			child := document.dictOf(kid)
+			if child == nil {
+				return errors.New("page tree references a missing node")
+			}
+			if err := walk(child, resources, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root, nil, 0); err != nil {
+		return pages, err
+	}
+	return pages, nil
+}
+
+// pageContents returns the decoded content streams of a page joined together.
+func (document *pdfDocument) pageContents(page pdfDict) []byte {
+	contents := document.resolve(page["Contents"])
+	streams := []any{contents}
+	if array, ok := contents.([]any); ok {
+		streams = array
+	}
+	var joined bytes.Buffer
+	for _, value := range streams {
+		stream, ok := document.resolve(value).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := document.decodeStream(stream)
+		if err != nil {
+			continue // Unsupported or corrupt streams contribute no text
+		}
+		joined.Write(decoded)
+		joined.WriteByte('\n')
+	}
+	return joined.Bytes()
+}
+
+// pdfTrailerWindow is how far from the end of the file the %%EOF marker may appear.
+const pdfTrailerWindow = 1024
+
+// validatePDF checks that data is a complete PDF with at least one page and returns the page count and
+// whether the xref had to be rebuilt. It checks the %PDF header and %%EOF trailer and resolves the page tree.
+// Broken xref offsets are common in vendor PDFs and viewers open them fine, so a document whose xref was
+// recovered by scanning is accepted as long as its pages still resolve.
+func validatePDF(data []byte) (int, bool, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return 0, false, errors.New("missing %PDF header")
+	}
+	if !bytes.Contains(data[max(0, len(data)-pdfTrailerWindow):], []byte("%%EOF")) {
+		return 0, false, errors.New("missing %%EOF trailer (file is probably truncated)")
+	}
+	document, err := openPDF(data)
+	if err != nil {
+		return 0, false, err
+	}
+	pages, err := document.pages()
+	if err != nil {
+		return 0, document.xrefRecovered, fmt.Errorf("page tree: %v", err)
+	}
+	if len(pages) == 0 {
+		return 0, document.xrefRecovered, errors.New("document has no pages")
+	}
+	return len(pages), document.xrefRecovered, nil
+}
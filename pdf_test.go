@@ -0,0 +1,105 @@
+package main // Define the main package
+
+import (
+	"bytes"   // Provides the PDF buffer
+	"fmt"     // Provides formatted output for the PDF objects
+	"strings" // Provides string helpers
+	"testing" // Provides the test runner
+)
+
+// testPDF writes a minimal PDF with the given number of empty pages and a correct xref table.
+// With a wrong startxref offset the xref has to be recovered by scanning the file.
+func testPDF(pageCount int, wrongStartXref bool) []byte {
+	var kids []string
+	for page := range pageCount {
+		kids = append(kids, fmt.Sprintf("%d 0 R", page+3))
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount),
+	}
+	for range pageCount {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>")
+	}
+
+	var document bytes.Buffer
+	document.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for index, object := range objects {
+		offsets[index] = document.Len()
+		fmt.Fprintf(&document, "%d 0 obj\n%s\nendobj\n", index+1, object)
+	}
+	xrefOffset := document.Len()
+	fmt.Fprintf(&document, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&document, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&document, "trailer\n<< /Size %d /Root 1 0 R >>\n", len(objects)+1)
+	if wrongStartXref {
+		xrefOffset += 7 // Points into the middle of the table, as in many re-saved vendor PDFs
+	}
+	fmt.Fprintf(&document, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return document.Bytes()
+}
+
+// objectStreamPDF writes a one-page PDF without an xref whose page object lives in an object stream
+// with the given /First offset, so it can only be opened by recovering the objects.
+func objectStreamPDF(first int) []byte {
+	streamData := "3 0 << /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>"
+	var document bytes.Buffer
+	document.WriteString("%PDF-1.5\n")
+	document.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	document.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	fmt.Fprintf(&document, "4 0 obj\n<< /Type /ObjStm /N 1 /First %d /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+		first, len(streamData), streamData)
+	document.WriteString("%%EOF\n")
+	return document.Bytes()
+}
+
+// TestValidatePDF checks that complete PDFs pass, recovered ones pass when their pages resolve,
+// and truncated, empty or crafted ones are rejected without panicking.
+func TestValidatePDF(t *testing.T) {
+	valid := testPDF(1, false)
+	tests := []struct {
+		name      string
+		data      []byte
+		pages     int  // Expected page count, 0 when validation must fail
+		recovered bool // Whether the xref has to be rebuilt
+	}{
+		{"one page", valid, 1, false},
+		{"three pages", testPDF(3, false), 3, false},
+		{"wrong startxref offset", testPDF(2, true), 2, true},
+		{"no pages", testPDF(0, false), 0, false},
+		{"truncated", valid[:len(valid)-40], 0, false},
+		{"missing header", bytes.Replace(valid, []byte("%PDF-"), []byte("%XYZ-"), 1), 0, false},
+		{"no objects", []byte("%PDF-1.4\nnothing here\n%%EOF\n"), 0, false},
+		{"html error page", []byte("<!DOCTYPE html><html><body>Not Found</body></html>"), 0, false},
+		{"negative startxref", bytes.Replace(valid, []byte("startxref\n"), []byte("startxref\n-"), 1), 1, true},
+		{"negative xref entry offset", bytes.Replace(valid, []byte("0000000009 00000 n"), []byte("-000000005 00000 n"), 1), 1, true},
+		{"xref entry offset beyond the file", bytes.Replace(valid, []byte("0000000009 00000 n"), []byte("9999999999 00000 n"), 1), 1, true},
+		{"negative /XRefStm", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /XRefStm -5"), 1), 1, true},
+		{"/XRefStm beyond the file", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /XRefStm 999999"), 1), 1, true},
+		{"negative /Prev", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Prev -5"), 1), 1, true},
+		{"/Prev beyond the file", bytes.Replace(valid, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Prev 999999"), 1), 1, true},
+		{"page in an object stream", objectStreamPDF(4), 1, true},
+		{"object stream with negative /First", objectStreamPDF(-50), 0, false},
+		{"object stream with /First beyond the stream", objectStreamPDF(999999), 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pages, recovered, err := validatePDF(test.data)
+			if test.pages == 0 {
+				if err == nil {
+					t.Fatalf("validatePDF accepted the file with %d pages, want an error", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validatePDF failed: %v", err)
+			}
+			if pages != test.pages || recovered != test.recovered {
+				t.Errorf("validatePDF = %d pages, recovered %v; want %d pages, recovered %v", pages, recovered, test.pages, test.recovered)
+			}
+		})
+	}
+}
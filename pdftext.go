@@ -0,0 +1,576 @@
+package main // Define the main package
+
+import (
+	"bytes"         // Provides bytes support
+	"fmt"           // Provides formatted keys for the font cache
+	"math"          // Provides math helpers for text positions
+	"strconv"       // Provides number parsing
+	"strings"       // Provides string manipulation functions
+	"unicode/utf16" // Provides UTF-16 decoding for ToUnicode CMaps
+)
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	codeBytes int             // Bytes per character code (1 for simple fonts, usually 2 for Type0)
+	toUnicode map[int]string  // Code → text from the /ToUnicode CMap
+	encoding  *[256]rune      // Simple font encoding with /Differences applied
+	widths    map[int]float64 // Code → glyph advance in thousandths of an em
+	missing   float64         // Advance of codes without an explicit width
+}
+
+// pdfWinAnsiHigh is WinAnsiEncoding for 0x80-0x9F; the rest of the upper half matches Latin-1.
+var pdfWinAnsiHigh = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+// pdfMacRomanHigh is MacRomanEncoding for 0x80-0xFF.
+var pdfMacRomanHigh = [128]rune{
+	0xC4, 0xC5, 0xC7, 0xC9, 0xD1, 0xD6, 0xDC, 0xE1, 0xE0, 0xE2, 0xE4, 0xE3, 0xE5, 0xE7, 0xE9, 0xE8,
+	0xEA, 0xEB, 0xED, 0xEC, 0xEE, 0xEF, 0xF1, 0xF3, 0xF2, 0xF4, 0xF6, 0xF5, 0xFA, 0xF9, 0xFB, 0xFC,
+	0x2020, 0xB0, 0xA2, 0xA3, 0xA7, 0x2022, 0xB6, 0xDF, 0xAE, 0xA9, 0x2122, 0xB4, 0xA8, 0x2260, 0xC6, 0xD8,
+	0x221E, 0xB1, 0x2264, 0x2265, 0xA5, 0xB5, 0x2202, 0x2211, 0x220F, 0x3C0, 0x222B, 0xAA, 0xBA, 0x3A9, 0xE6, 0xF8,
+	0xBF, 0xA1, 0xAC, 0x221A, 0x192, 0x2248, 0x2206, 0xAB, 0xBB, 0x2026, 0xA0, 0xC0, 0xC3, 0xD5, 0x152, 0x153,
+	0x2013, 0x2014, 0x201C, 0x201D, 0x2018, 0x2019, 0xF7, 0x25CA, 0xFF, 0x178, 0x2044, 0x20AC, 0x2039, 0x203A, 0xFB01, 0xFB02,
+	0x2021, 0xB7, 0x201A, 0x201E, 0x2030, 0xC2, 0xCA, 0xC1, 0xCB, 0xC8, 0xCD, 0xCE, 0xCF, 0xCC, 0xD3, 0xD4,
+	0xF8FF, 0xD2, 0xDA, 0xDB, 0xD9, 0x131, 0x2C6, 0x2DC, 0xAF, 0x2D8, 0x2D9, 0x2DA, 0xB8, 0x2DD, 0x2DB, 0x2C7,
+}
+
+// pdfGlyphNames maps the glyph names used in /Differences to text; single letters map to themselves.
+var pdfGlyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '’', "parenleft": '(', "parenright": ')',
+	"asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "minus": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7',
+	"eight": '8', "nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"asciicircum": '^', "underscore": '_', "grave": '`', "quoteleft": '‘', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "bullet": '•', "endash": '–', "emdash": '—',
+	"quotedblleft": '“', "quotedblright": '”', "quotesinglbase": '‚', "quotedblbase": '„',
+	"ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ', "degree": '°', "copyright": '©', "registered": '®',
+	"trademark": '™', "plusminus": '±', "multiply": '×', "divide": '÷', "mu": 'µ', "section": '§',
+	"paragraph": '¶', "periodcentered": '·', "nbspace": ' ', "dagger": '†', "daggerdbl": '‡',
+	"Euro": '€', "sterling": '£', "yen": '¥', "cent": '¢', "Omega": 'Ω', "micro": 'µ',
+	"adieresis": 'ä', "odieresis": 'ö', "udieresis": 'ü', "Adieresis": 'Ä', "Odieresis": 'Ö',
+	"Udieresis": 'Ü', "germandbls": 'ß', "eacute": 'é', "egrave": 'è', "agrave": 'à', "ccedilla": 'ç',
+}
+
+// newPDFFont builds the decoder for a font dictionary.
+func (document *pdfDocument) newPDFFont(fontDict pdfDict) *pdfFont {
+	font := &pdfFont{codeBytes: 1, widths: make(map[int]float64), missing: 500}
+	if fontDict == nil {
+		font.encoding = pdfBaseEncoding("")
+		return font
+	}
+	if fontDict["Subtype"] == pdfName("Type0") {
+		font.codeBytes = 2 // Identity-H and the common CJK CMaps use two-byte codes
+	}
+
+	// Simple fonts: a named base encoding plus /Differences
+	encodingName := ""
+	var differences []any
+	switch encoding := document.resolve(fontDict["Encoding"]).(type) {
+	case pdfName:
+		encodingName = string(encoding)
+	case pdfDict:
+		if base, ok := document.resolve(encoding["BaseEncoding"]).(pdfName); ok {
+			encodingName = string(base)
+		}
+		differences, _ = document.resolve(encoding["Differences"]).([]any)
+	}
+	font.encoding = pdfBaseEncoding(encodingName)
+	code := 0
+	for _, item := range differences {
+		switch value := document.resolve(item).(type) {
+		case float64:
+			code = int(value)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				if character, ok := pdfGlyphRune(string(value)); ok {
+					font.encoding[code] = character
+				}
+			}
+			code++
+		}
+	}
+
+	document.loadFontWidths(font, fontDict)
+
+	// A ToUnicode CMap takes precedence over everything else
+	if stream, ok := document.resolve(fontDict["ToUnicode"]).(*pdfStream); ok {
+		if decoded, err := document.decodeStream(stream); err == nil {
+			font.toUnicode, font.codeBytes = parseToUnicodeCMap(decoded, font.codeBytes)
+		}
+	}
+	return font
+}
+
+// loadFontWidths reads /FirstChar and /Widths of simple fonts, or /DW and /W of the descendant CID font.
+// Widths only matter for telling word gaps from kerning, so anything missing falls back to an average glyph.
+func (document *pdfDocument) loadFontWidths(font *pdfFont, fontDict pdfDict) {
+	if descendants, ok := document.resolve(fontDict["DescendantFonts"]).([]any); ok && len(descendants) > 0 {
+		cidFont := document.dictOf(descendants[0])
+		font.missing = 1000
+		if value, ok := document.resolve(cidFont["DW"]).(float64); ok {
+			font.missing = value
+		}
+		// /W holds "first [w1 w2 ...]" and "first last w" groups
+		widths, _ := document.resolve(cidFont["W"]).([]any)
+		for index := 0; index+1 < len(widths); {
+			first, _ := document.resolve(widths[index]).(float64)
+			if list, ok := document.resolve(widths[index+1]).([]any); ok {
+				for offset, item := range list {
+					if width, ok := document.resolve(item).(float64); ok {
+						font.widths[int(first)+offset] = width
+					}
+				}
+				index += 2
+				continue
+			}
+			if index+2 >= len(widths) {
+				break
+			}
+			last, _ := document.resolve(widths[index+1]).(float64)
+			width, _ := document.resolve(widths[index+2]).(float64)
+			for code := int(first); code <= int(last) && code-int(first) < 0x10000; code++ {
+				font.widths[code] = width
+			}
+			index += 3
+		}
+		return
+	}
+
+	if descriptor := document.dictOf(fontDict["FontDescriptor"]); descriptor != nil {
+		if value, ok := document.resolve(descriptor["MissingWidth"]).(float64); ok && value > 0 {
+			font.missing = value
+		}
+	}
+	firstChar, _ := document.resolve(fontDict["FirstChar"]).(float64)
+	widths, _ := document.resolve(fontDict["Widths"]).([]any)
+	for offset, item := range widths {
+		if width, ok := document.resolve(item).(float64); ok {
+			font.widths[int(firstChar)+offset] = width
+		}
+	}
+}
+
+// pdfBaseEncoding returns a copy of a named encoding. StandardEncoding and unknown names use Latin-1
+// with WinAnsi punctuation, which is close enough for searching.
+func pdfBaseEncoding(name string) *[256]rune {
+	var table [256]rune
+	for code := range table {
+		table[code] = rune(code)
+	}
+	switch name {
+	case "MacRomanEncoding":
+		for code, character := range pdfMacRomanHigh {
+			table[0x80+code] = character
+		}
+	default:
+		for code, character := range pdfWinAnsiHigh {
+			table[0x80+code] = character
+		}
+		if name == "StandardEncoding" {
+			table['\''] = '’'
+			table['`'] = '‘'
+		}
+	}
+	return &table
+}
+
+// pdfGlyphRune maps a glyph name (including uniXXXX and uXXXX forms) to a character.
+func pdfGlyphRune(name string) (rune, bool) {
+	if character, ok := pdfGlyphNames[name]; ok {
+		return character, true
+	}
+	if len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		return rune(name[0]), true
+	}
+	// Suffixes such as "a.sc" or "one.oldstyle" name variants of the base glyph
+	if dot := strings.IndexByte(name, '.'); dot > 0 {
+		return pdfGlyphRune(name[:dot])
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hexDigits, found := strings.CutPrefix(name, prefix); found && len(hexDigits) >= 4 && len(hexDigits) <= 6 {
+			if value, err := strconv.ParseUint(hexDigits[:4], 16, 32); err == nil && prefix == "uni" {
+				return rune(value), true
+			}
+			if value, err := strconv.ParseUint(hexDigits, 16, 32); err == nil {
+				return rune(value), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseToUnicodeCMap reads bfchar and bfrange mappings and the code width from codespacerange.
+func parseToUnicodeCMap(data []byte, defaultBytes int) (map[int]string, int) {
+	mapping := make(map[int]string)
+	codeBytes := defaultBytes
+	lexer := &pdfLexer{data: data}
+	var operands []any
+	mode := ""
+	for {
+		object, err := lexer.readObject()
+		if err != nil {
+			break
+		}
+		keyword, isKeyword := object.(pdfKeyword)
+		if !isKeyword {
+			if mode != "" {
+				operands = append(operands, object)
+			}
+			continue
+		}
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			mode = string(keyword)
+			operands = operands[:0]
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if low, ok := operands[0].(pdfString); ok && len(low) > 0 {
+					codeBytes = len(low)
+				}
+			}
+			mode = ""
+		case "endbfchar":
+			for index := 0; index+1 < len(operands); index += 2 {
+				source, sourceOK := operands[index].(pdfString)
+				target, targetOK := operands[index+1].(pdfString)
+				if sourceOK && targetOK {
+					mapping[pdfCode(source)] = decodeUTF16BE(target)
+				}
+			}
+			mode = ""
+		case "endbfrange":
+			for index := 0; index+2 < len(operands); index += 3 {
+				low, lowOK := operands[index].(pdfString)
+				high, highOK := operands[index+1].(pdfString)
+				if !lowOK || !highOK {
+					continue
+				}
+				first, last := pdfCode(low), pdfCode(high)
+				if last < first || last-first > 0xFFFF {
+					continue // Ignore nonsensical ranges
+				}
+				switch target := operands[index+2].(type) {
+				case pdfString:
+					// Consecutive codes map to consecutive characters
+					base := []rune(decodeUTF16BE(target))
+					if len(base) == 0 {
+						continue
+					}
+					for code := first; code <= last; code++ {
+						characters := append([]rune{}, base...)
+						characters[len(characters)-1] += rune(code - first)
+						mapping[code] = string(characters)
+					}
+				case []any:
+					for offset, item := range target {
+						if text, ok := item.(pdfString); ok && first+offset <= last {
+							mapping[first+offset] = decodeUTF16BE(text)
+						}
+					}
+				}
+			}
+			mode = ""
+		}
+	}
+	return mapping, codeBytes
+}
+
+// pdfCode turns the bytes of a character code into an integer.
+func pdfCode(raw pdfString) int {
+	code := 0
+	for index := 0; index < len(raw); index++ {
+		code = code<<8 | int(raw[index])
+	}
+	return code
+}
+
+// decodeUTF16BE decodes the UTF-16BE text of a CMap target.
+func decodeUTF16BE(raw pdfString) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for index := 0; index+1 < len(raw); index += 2 {
+		units = append(units, uint16(raw[index])<<8|uint16(raw[index+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfGlyph is one character code of a shown string with its text and advance.
+type pdfGlyph struct {
+	Text    string
+	Advance float64 // Thousandths of an em
+	Space   bool    // Single-byte code 32, which word spacing applies to
+}
+
+// glyphs splits the bytes of a shown string into character codes and maps them to text.
+func (font *pdfFont) glyphs(raw pdfString) []pdfGlyph {
+	width := 1
+	if font.codeBytes > 1 {
+		width = font.codeBytes
+	}
+	glyphs := make([]pdfGlyph, 0, len(raw)/width)
+	for index := 0; index+width <= len(raw); index += width {
+		code := pdfCode(raw[index : index+width])
+		glyph := pdfGlyph{Advance: font.missing, Space: width == 1 && code == ' '}
+		if advance, found := font.widths[code]; found {
+			glyph.Advance = advance
+		}
+		if mapped, found := font.toUnicode[code]; found {
+			glyph.Text = mapped
+		} else if width == 1 {
+			// Two-byte codes without a ToUnicode entry cannot be turned into text
+			if character := font.encoding[code]; character >= ' ' || character == '\t' {
+				glyph.Text = string(character)
+			}
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	return glyphs
+}
+
+// pdfMatrix is a PDF transformation matrix [a b c d e f].
+type pdfMatrix [6]float64
+
+// pdfIdentity is the identity matrix.
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// translate returns the matrix moved by (x, y) in its own coordinate space.
+func (matrix pdfMatrix) translate(x, y float64) pdfMatrix {
+	matrix[4] += x*matrix[0] + y*matrix[2]
+	matrix[5] += x*matrix[1] + y*matrix[3]
+	return matrix
+}
+
+// pdfTextState is the text state of one content stream (the parts that matter for layout).
+type pdfTextState struct {
+	font        *pdfFont
+	fontSize    float64
+	charSpacing float64 // Tc
+	wordSpacing float64 // Tw
+	hScale      float64 // Tz / 100
+	leading     float64 // TL
+	matrix      pdfMatrix
+	lineMatrix  pdfMatrix
+}
+
+// pdfTextExtractor walks content streams and collects the text of one page.
+type pdfTextExtractor struct {
+	document *pdfDocument
+	fonts    map[string]*pdfFont // Decoders keyed by resource dictionary identity and font name
+	text     strings.Builder
+	lastX    float64 // Where the last shown glyph ended, in text space of the current stream
+	lastY    float64
+	haveLast bool
+}
+
+// extractPageText returns the text of every page of the document, in page order.
+// Pages whose text was converted to outlines have no fonts and yield empty strings.
+func (document *pdfDocument) extractPageText() ([]string, error) {
+	pages, err := document.pages()
+	texts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		extractor := &pdfTextExtractor{document: document, fonts: make(map[string]*pdfFont)}
+		extractor.run(document.pageContents(page.Dict), page.Resources, 0)
+		texts = append(texts, normalizePDFText(extractor.text.String()))
+	}
+	return texts, err
+}
+
+// pdfLigatures spells out the ligature characters many fonts map their glyphs to.
+var pdfLigatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st")
+
+// normalizePDFText spells out ligatures and collapses runs of spaces and blank lines.
+func normalizePDFText(text string) string {
+	lines := strings.Split(pdfLigatures.Replace(text), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// font returns the decoder for a font resource name.
+func (extractor *pdfTextExtractor) font(resources pdfDict, name string) *pdfFont {
+	// Form XObjects may reuse a font name for a different font, so the key includes the dictionary
+	key := fmt.Sprintf("%p/%s", resources, name)
+	if font, found := extractor.fonts[key]; found {
+		return font
+	}
+	fonts := extractor.document.dictOf(resources["Font"])
+	font := extractor.document.newPDFFont(extractor.document.dictOf(fonts[name]))
+	extractor.fonts[key] = font
+	return font
+}
+
+// separate inserts a line break or space unless the text already ends with one.
+func (extractor *pdfTextExtractor) separate(separator string) {
+	current := extractor.text.String()
+	if current == "" || strings.HasSuffix(current, "\n") || (separator == " " && strings.HasSuffix(current, " ")) {
+		return
+	}
+	extractor.text.WriteString(separator)
+}
+
+// show appends the text of a string at the current text matrix, inserting a line break when the
+// baseline moved and a space when the gap to the previous glyph is wider than kerning.
+func (extractor *pdfTextExtractor) show(state *pdfTextState, raw pdfString) {
+	if state.font == nil {
+		return
+	}
+	// Scale of the text matrix turns text space distances into comparable units
+	scale := math.Hypot(state.matrix[0], state.matrix[1])
+	size := math.Abs(state.fontSize) * max(scale, math.Hypot(state.matrix[2], state.matrix[3]))
+	if size == 0 {
+		size = 1
+	}
+	x, y := state.matrix[4], state.matrix[5]
+	if extractor.haveLast {
+		switch {
+		case math.Abs(y-extractor.lastY) > size*0.5:
+			extractor.separate("\n")
+		case x-extractor.lastX > size*0.15 || extractor.lastX-x > size:
+			extractor.separate(" ")
+		}
+	}
+	for _, glyph := range state.font.glyphs(raw) {
+		extractor.text.WriteString(glyph.Text)
+		advance := glyph.Advance/1000*state.fontSize + state.charSpacing
+		if glyph.Space {
+			advance += state.wordSpacing
+		}
+		state.matrix = state.matrix.translate(advance*state.hScale, 0)
+	}
+	extractor.lastX, extractor.lastY, extractor.haveLast = state.matrix[4], state.matrix[5], true
+}
+
+// run interprets a content stream; depth limits nested form XObjects.
+func (extractor *pdfTextExtractor) run(content []byte, resources pdfDict, depth int) {
+	lexer := &pdfLexer{data: content}
+	state := &pdfTextState{hScale: 1, matrix: pdfIdentity, lineMatrix: pdfIdentity}
+	extractor.haveLast = false // Positions are not comparable across streams
+	var operands []any
+	number := func(index int) float64 {
+		if index < len(operands) {
+			value, _ := operands[index].(float64)
+			return value
+		}
+		return 0
+	}
+	nextLine := func(x, y float64) {
+		state.lineMatrix = state.lineMatrix.translate(x, y)
+		state.matrix = state.lineMatrix
+	}
+	for {
+		object, err := lexer.readObject()
+		if err != nil {
+			return
+		}
+		operator, isOperator := object.(pdfKeyword)
+		if !isOperator {
+			operands = append(operands, object)
+			continue
+		}
+		switch operator {
+		case "BT":
+			state.matrix, state.lineMatrix = pdfIdentity, pdfIdentity
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					state.font = extractor.font(resources, string(name))
+				}
+				state.fontSize = number(1)
+			}
+		case "Tc":
+			state.charSpacing = number(0)
+		case "Tw":
+			state.wordSpacing = number(0)
+		case "Tz":
+			state.hScale = number(0) / 100
+		case "TL":
+			state.leading = number(0)
+		case "Td":
+			nextLine(number(0), number(1))
+		case "TD":
+			state.leading = -number(1)
+			nextLine(number(0), number(1))
+		case "Tm":
+			if len(operands) >= 6 {
+				for index := range state.matrix {
+					state.matrix[index] = number(index)
+				}
+				state.lineMatrix = state.matrix
+			}
+		case "T*":
+			nextLine(0, -state.leading)
+		case "Tj", "'", "\"":
+			if operator == "\"" && len(operands) >= 3 {
+				state.wordSpacing, state.charSpacing = number(0), number(1)
+			}
+			if operator != "Tj" {
+				nextLine(0, -state.leading)
+			}
+			if len(operands) > 0 {
+				if raw, ok := operands[len(operands)-1].(pdfString); ok {
+					extractor.show(state, raw)
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[0].([]any)
+				for _, item := range items {
+					switch value := item.(type) {
+					case pdfString:
+						extractor.show(state, value)
+					case float64:
+						// Adjustments are in thousandths of an em, positive values move left
+						state.matrix = state.matrix.translate(-value/1000*state.fontSize*state.hScale, 0)
+					}
+				}
+			}
+		case "Do":
+			// Form XObjects carry their own content stream (and often the actual text)
+			if depth < 4 && len(operands) > 0 {
+				name, _ := operands[0].(pdfName)
+				xobjects := extractor.document.dictOf(resources["XObject"])
+				if form, ok := extractor.document.resolve(xobjects[string(name)]).(*pdfStream); ok && form.Dict["Subtype"] == pdfName("Form") {
+					formResources := resources
+					if own := extractor.document.dictOf(form.Dict["Resources"]); own != nil {
+						formResources = own
+					}
+					if decoded, err := extractor.document.decodeStream(form); err == nil {
+						extractor.separate("\n")
+						extractor.run(decoded, formResources, depth+1)
+						extractor.separate("\n")
+						extractor.haveLast = false
+					}
+				}
+			}
+		case "BI":
+			// Inline images: skip the binary data between ID and the EI that follows whitespace
+			for {
+				keyword, err := lexer.readObject()
+				if err != nil || keyword == pdfKeyword("ID") {
+					break
+				}
+			}
+			search := lexer.position
+			for {
+				end := bytes.Index(lexer.data[search:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				end += search
+				if isPDFWhitespace(lexer.data[end-1]) && (end+2 >= len(lexer.data) || isPDFWhitespace(lexer.data[end+2])) {
+					lexer.position = end + 2
+					break
+				}
+				search = end + 2
+			}
+		}
+		operands = operands[:0]
+	}
+}
@@ -0,0 +1,245 @@
+package main // Define the main package
+
+import (
+	"errors"        // Provides error values
+	"image"         // Provides the image types
+	"image/color"   // Provides the model colour
+	"image/png"     // Encodes the previews
+	"log"           // Provides logging functions
+	"math"          // Provides the projection maths
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"strconv"       // Provides the size in preview file names
+	"strings"       // Provides string manipulation functions
+)
+
+// previewsDir holds the rendered previews of 3D models, named by content hash and size.
+const previewsDir = "previews/"
+
+// defaultPreviewSize is the edge length of the square previews in pixels.
+const defaultPreviewSize = 256
+
+// previewSupersampling renders at this multiple of the output size and averages down for smooth edges.
+const previewSupersampling = 2
+
+// previewColor is the base colour of the shaded model.
+var previewColor = [3]float64{70, 130, 180}
+
+// previewPath returns where the preview of a file with the given hash is stored.
+func previewPath(sum string, size int) string {
+	return previewsDir + sum + "-" + strconv.Itoa(size) + ".png"
+}
+
+// updatePreviews renders a preview for every STL in the manifest whose contents have no preview yet
+// at the requested size, and removes previews no entry refers to any more.
+func updatePreviews(archive *archiveManifest, size int) bool {
+	if !directoryExists(previewsDir) {
+		createDirectory(previewsDir, 0o755)
+	}
+	referenced := make(map[string]bool)
+	rendered := 0
+	for _, entry := range archive.Entries {
+		entry.Preview = ""
+		if entry.AssetType != "stl" || entry.SHA256 == "" || (entry.Validation != nil && !entry.Validation.Valid) {
+			continue
+		}
+		preview := previewPath(entry.SHA256, size)
+		if !fileExists(filepath.FromSlash(preview)) {
+			if !renderSTLPreviewFile(filepath.FromSlash(entry.Path), filepath.FromSlash(preview), size) {
+				continue
+			}
+			rendered++
+		}
+		entry.Preview = preview
+		referenced[filepath.Base(preview)] = true
+	}
+
+	// Remove previews of old contents or old sizes
+	files, err := os.ReadDir(previewsDir)
+	if err != nil {
+		log.Printf("Failed to list %s: %v", previewsDir, err)
+		return false
+	}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".png") && !referenced[file.Name()] {
+			if err := os.Remove(filepath.Join(previewsDir, file.Name())); err != nil {
+				log.Printf("Failed to remove stale preview %s: %v", file.Name(), err)
+			}
+		}
+	}
+	log.Printf("Rendered %d new previews into %s (%d in use)", rendered, previewsDir, len(referenced))
+	return true
+}
+
+// renderSTLPreviewFile renders the STL at modelPath to a PNG at previewFile.
+func renderSTLPreviewFile(modelPath, previewFile string, size int) bool {
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		log.Printf("Failed to read %s for a preview: %v", modelPath, err)
+		return false
+	}
+	mesh, err := parseSTL(data)
+	if err != nil {
+		log.Printf("Failed to parse %s for a preview: %v", modelPath, err)
+		return false
+	}
+	preview, err := renderSTLPreview(mesh, size)
+	if err != nil {
+		log.Printf("Failed to render %s: %v", modelPath, err)
+		return false
+	}
+	// Write through a temporary file so an interrupted run never leaves a half-written preview
+	temporaryFile := previewFile + ".tmp"
+	out, err := os.Create(temporaryFile)
+	if err != nil {
+		log.Printf("Failed to create %s: %v", temporaryFile, err)
+		return false
+	}
+	if err := png.Encode(out, preview); err != nil {
+		out.Close()
+		os.Remove(temporaryFile)
+		log.Printf("Failed to encode preview of %s: %v", modelPath, err)
+		return false
+	}
+	out.Close()
+	if err := os.Rename(temporaryFile, previewFile); err != nil {
+		log.Printf("Failed to save %s: %v", previewFile, err)
+		return false
+	}
+	return true
+}
+
+// renderSTLPreview rasterizes the mesh in isometric projection (Z up) with flat Lambert shading on a
+// transparent background. Facets are lit from both sides, so meshes with flipped normals still look solid.
+func renderSTLPreview(mesh *stlMesh, size int) (*image.RGBA, error) {
+	if len(mesh.Triangles) == 0 {
+		return nil, errors.New("mesh has no triangles")
+	}
+	if size < 16 || size > 4096 {
+		return nil, errors.New("preview size must be between 16 and 4096 pixels")
+	}
+
+	// Isometric camera: looking down the (-1, 1, -1) diagonal with Z pointing up on screen
+	eye := normalize3([3]float64{1, -1, 1})
+	right := normalize3(cross3([3]float64{0, 0, 1}, eye))
+	up := cross3(eye, right)
+	light := normalize3([3]float64{
+		-0.3*right[0] + 0.6*up[0] + 0.75*eye[0],
+		-0.3*right[1] + 0.6*up[1] + 0.75*eye[1],
+		-0.3*right[2] + 0.6*up[2] + 0.75*eye[2],
+	})
+
+	// Project every vertex and fit the projection into the canvas with a small margin
+	type projected struct{ X, Y, Depth float64 }
+	projection := make([][3]projected, len(mesh.Triangles))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for index, triangle := range mesh.Triangles {
+		for vertex, position := range triangle.Vertices {
+			point := [3]float64{float64(position[0]), float64(position[1]), float64(position[2])}
+			projected := projected{X: dot3(point, right), Y: dot3(point, up), Depth: dot3(point, eye)}
+			projection[index][vertex] = projected
+			minX, maxX = math.Min(minX, projected.X), math.Max(maxX, projected.X)
+			minY, maxY = math.Min(minY, projected.Y), math.Max(maxY, projected.Y)
+		}
+	}
+	canvas := size * previewSupersampling
+	extent := math.Max(maxX-minX, maxY-minY)
+	if extent <= 0 || math.IsNaN(extent) || math.IsInf(extent, 0) {
+		return nil, errors.New("mesh has no extent")
+	}
+	scale := float64(canvas) * 0.9 / extent
+	centerX, centerY := (minX+maxX)/2, (minY+maxY)/2
+	toScreen := func(point projected) (float64, float64) {
+		return float64(canvas)/2 + (point.X-centerX)*scale, float64(canvas)/2 - (point.Y-centerY)*scale
+	}
+
+	// Rasterize with a depth buffer; larger depth is closer to the viewer
+	depth := make([]float64, canvas*canvas)
+	for index := range depth {
+		depth[index] = math.Inf(-1)
+	}
+	shade := make([]float64, canvas*canvas)
+	for index, triangle := range mesh.Triangles {
+		var corners [3][3]float64
+		for vertex, position := range triangle.Vertices {
+			corners[vertex] = [3]float64{float64(position[0]), float64(position[1]), float64(position[2])}
+		}
+		normal := cross3(subtract3(corners[1], corners[0]), subtract3(corners[2], corners[0]))
+		if length3(normal) == 0 {
+			continue // Degenerate facet
+		}
+		normal = normalize3(normal)
+		if dot3(normal, eye) < 0 {
+			normal = [3]float64{-normal[0], -normal[1], -normal[2]}
+		}
+		intensity := 0.3 + 0.7*math.Max(0, dot3(normal, light))
+
+		x0, y0 := toScreen(projection[index][0])
+		x1, y1 := toScreen(projection[index][1])
+		x2, y2 := toScreen(projection[index][2])
+		area := (x1-x0)*(y2-y0) - (x2-x0)*(y1-y0)
+		if area == 0 {
+			continue
+		}
+		minPixelX := max(0, int(math.Floor(math.Min(x0, math.Min(x1, x2)))))
+		maxPixelX := min(canvas-1, int(math.Ceil(math.Max(x0, math.Max(x1, x2)))))
+		minPixelY := max(0, int(math.Floor(math.Min(y0, math.Min(y1, y2)))))
+		maxPixelY := min(canvas-1, int(math.Ceil(math.Max(y0, math.Max(y1, y2)))))
+		for y := minPixelY; y <= maxPixelY; y++ {
+			for x := minPixelX; x <= maxPixelX; x++ {
+				// Barycentric weights at the pixel centre
+				px, py := float64(x)+0.5, float64(y)+0.5
+				weight0 := ((x1-px)*(y2-py) - (x2-px)*(y1-py)) / area
+				weight1 := ((x2-px)*(y0-py) - (x0-px)*(y2-py)) / area
+				weight2 := 1 - weight0 - weight1
+				if weight0 < 0 || weight1 < 0 || weight2 < 0 {
+					continue
+				}
+				pixelDepth := weight0*projection[index][0].Depth + weight1*projection[index][1].Depth + weight2*projection[index][2].Depth
+				if pixelDepth > depth[y*canvas+x] {
+					depth[y*canvas+x] = pixelDepth
+					shade[y*canvas+x] = intensity
+				}
+			}
+		}
+	}
+
+	// Average each block of samples into one pixel; uncovered samples are transparent
+	preview := image.NewRGBA(image.Rect(0, 0, size, size))
+	samples := float64(previewSupersampling * previewSupersampling)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var red, green, blue, alpha float64
+			for sampleY := 0; sampleY < previewSupersampling; sampleY++ {
+				for sampleX := 0; sampleX < previewSupersampling; sampleX++ {
+					index := (y*previewSupersampling+sampleY)*canvas + x*previewSupersampling + sampleX
+					if math.IsInf(depth[index], -1) {
+						continue
+					}
+					red += previewColor[0] * shade[index]
+					green += previewColor[1] * shade[index]
+					blue += previewColor[2] * shade[index]
+					alpha += 255
+				}
+			}
+			// RGBA is premultiplied, so the colour sums are averaged over all samples
+			preview.SetRGBA(x, y, color.RGBA{
+				R: uint8(math.Min(255, red/samples)),
+				G: uint8(math.Min(255, green/samples)),
+				B: uint8(math.Min(255, blue/samples)),
+				A: uint8(alpha / samples),
+			})
+		}
+	}
+	return preview, nil
+}
+
+// normalize3 returns a scaled to unit length.
+func normalize3(a [3]float64) [3]float64 {
+	length := length3(a)
+	if length == 0 {
+		return a
+	}
+	return [3]float64{a[0] / length, a[1] / length, a[2] / length}
+}
@@ -0,0 +1,82 @@
+package main // Define the main package
+
+import (
+	"net/url" // Provides URL parsing and encoding
+	"strings" // Provides string manipulation functions
+
+	"golang.org/x/net/html" // Provides HTML parsing functions
+)
+
+// documentBaseURL returns the URL that relative links in the document resolve against.
+// It is the page URL, overridden by the first <base href> in the document (itself resolved against the page URL).
+func documentBaseURL(document *html.Node, pageURL string) *url.URL {
+	// Start from the URL the page was fetched from
+	baseURL, err := url.Parse(pageURL)
+	if err != nil || pageURL == "" {
+		baseURL = nil
+	}
+
+	// Recursive function to find the first <base> tag with an href
+	var findBase func(*html.Node) string
+	findBase = func(node *html.Node) string {
+		if node.Type == html.ElementNode && node.Data == "base" {
+			for _, attribute := range node.Attr {
+				if attribute.Key == "href" && strings.TrimSpace(attribute.Val) != "" {
+					return strings.TrimSpace(attribute.Val)
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if href := findBase(child); href != "" {
+				return href
+			}
+		}
+		return ""
+	}
+
+	// Apply the <base href> if the document declares one
+	if baseHref := findBase(document); baseHref != "" {
+		if resolved := resolveLinkURL(baseURL, baseHref); resolved != "" {
+			if parsedBase, err := url.Parse(resolved); err == nil {
+				baseURL = parsedBase
+			}
+		}
+	}
+	return baseURL
+}
+
+// resolveLinkURL resolves a link found on a page against the page's base URL and returns an absolute URL.
+// Protocol-relative links ("//cdn.shopify.com/...") take the base URL's scheme, or https without a base.
+// It returns "" for links that can never point at a file (fragments, javascript:, mailto:, data:).
+func resolveLinkURL(baseURL *url.URL, rawLink string) string {
+	// Trim any surrounding whitespace from the link
+	link := strings.TrimSpace(rawLink)
+	lowerLink := strings.ToLower(link)
+	if link == "" || strings.HasPrefix(link, "#") ||
+		strings.HasPrefix(lowerLink, "javascript:") ||
+		strings.HasPrefix(lowerLink, "mailto:") ||
+		strings.HasPrefix(lowerLink, "tel:") ||
+		strings.HasPrefix(lowerLink, "data:") {
+		return ""
+	}
+
+	// Parse the link itself
+	reference, err := url.Parse(link)
+	if err != nil {
+		return "" // Unparseable links are skipped
+	}
+
+	// Without a base only absolute and protocol-relative links can be resolved
+	if baseURL == nil {
+		if strings.HasPrefix(link, "//") {
+			reference.Scheme = "https"
+		}
+		if !reference.IsAbs() {
+			return ""
+		}
+		return reference.String()
+	}
+
+	// Resolve relative paths, "../" segments and protocol-relative links against the base
+	return baseURL.ResolveReference(reference).String()
+}
@@ -0,0 +1,422 @@
+package main // Define the main package
+
+import (
+	"bytes"         // Provides request bodies and buffers
+	"crypto/hmac"   // Provides the HMAC of the request signature
+	"crypto/sha256" // Provides the hashes of the request signature
+	"encoding/hex"  // Provides hex encoding of hashes
+	"encoding/xml"  // Provides the S3 XML request and response bodies
+	"errors"        // Provides error values
+	"fmt"           // Provides formatted errors
+	"io"            // Provides basic interfaces to I/O primitives
+	"io/fs"         // Provides fs.ErrNotExist
+	"maps"          // Provides map iteration helpers
+	"net/http"      // Provides HTTP client and server implementations
+	"net/url"       // Provides URL parsing and encoding
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"slices"        // Provides slice sorting helpers
+	"strconv"       // Provides number formatting for query parameters
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides time-related functions
+)
+
+// s3PartSize is the size of multipart upload parts; files up to this size are sent in one presigned PUT.
+// S3 requires parts of at least 5 MiB except the last one.
+const s3PartSize = 16 << 20
+
+// s3PresignExpiry is how long a presigned request stays valid.
+const s3PresignExpiry = 15 * time.Minute
+
+// s3Storage stores files in an S3-compatible bucket (AWS S3, MinIO, Ceph, R2, ...) using path-style
+// addressing. Every request is a presigned URL signed with AWS Signature Version 4, so no SDK is needed.
+// Small files are spooled locally and sent with a presigned PUT on commit; large files are sent as
+// multipart uploads whose parts go up immediately but which are only completed on commit.
+// Either way nothing appears in the bucket before commit.
+type s3Storage struct {
+	endpoint     *url.URL     // Service root, e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	bucket       string       // Bucket name
+	prefix       string       // Key prefix every name is stored under, empty or ending in "/"
+	region       string       // Signing region
+	accessKey    string       // AWS_ACCESS_KEY_ID
+	secretKey    string       // AWS_SECRET_ACCESS_KEY
+	sessionToken string       // AWS_SESSION_TOKEN for temporary credentials, optional
+	client       *http.Client // Client for every request
+	staged       []*s3Upload  // Uploads waiting for commit
+}
+
+// s3Upload is one file put into the bucket but not yet committed.
+type s3Upload struct {
+	key       string            // Full object key
+	spoolPath string            // Local copy of a small file, sent with a single PUT on commit
+	uploadID  string            // Multipart upload of a large file, completed on commit
+	parts     []s3CompletedPart // Parts uploaded so far
+}
+
+// s3CompletedPart is one uploaded part, listed again when the multipart upload is completed.
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// s3CompleteMultipartUpload is the body of the request that completes a multipart upload.
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// s3InitiateMultipartUploadResult is the response to starting a multipart upload.
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+// s3ListBucketResult is one page of a ListObjectsV2 response.
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// newS3Storage returns the storage for a destination of the form s3://bucket/prefix. Credentials come from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN; the region from the argument,
+// AWS_REGION or AWS_DEFAULT_REGION (default us-east-1); the endpoint from the argument, AWS_ENDPOINT_URL
+// or the regional AWS endpoint.
+func newS3Storage(destination, endpoint, region string) (*s3Storage, error) {
+	location, err := url.Parse(destination)
+	if err != nil || location.Scheme != "s3" || location.Host == "" {
+		return nil, fmt.Errorf("invalid S3 destination %q (expected s3://bucket/prefix)", destination)
+	}
+	prefix := strings.Trim(location.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	region = firstNonEmpty(region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	endpoint = firstNonEmpty(endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"), "https://s3."+region+".amazonaws.com")
+	endpointURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || endpointURL.Host == "" || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	storage := &s3Storage{
+		endpoint:     endpointURL,
+		bucket:       location.Host,
+		prefix:       prefix,
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 10 * time.Minute},
+	}
+	if storage.accessKey == "" || storage.secretKey == "" {
+		return nil, errors.New("S3 storage needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return storage, nil
+}
+
+// firstNonEmpty returns the first of the values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// objectURL returns the presigned URL of a request on an object key (or the bucket, for an empty key).
+func (storage *s3Storage) objectURL(method, key string, query url.Values) string {
+	canonicalPath := storage.endpoint.Path + "/" + awsURIEncode(storage.bucket, false)
+	if key != "" {
+		canonicalPath += "/" + awsURIEncode(key, false)
+	}
+	return presignS3URL(method, storage.endpoint.Scheme, storage.endpoint.Host, canonicalPath, query,
+		storage.region, storage.accessKey, storage.secretKey, storage.sessionToken, time.Now(), s3PresignExpiry)
+}
+
+// presignS3URL signs a request with AWS Signature Version 4 in the query string. The payload is left unsigned,
+// so the URL can be handed to any HTTP client; canonicalPath must already be URI-encoded.
+func presignS3URL(method, scheme, host, canonicalPath string, query url.Values, region, accessKey, secretKey, sessionToken string, now time.Time, expiry time.Duration) string {
+	now = now.UTC()
+	date := now.Format("20060102")
+	timestamp := now.Format("20060102T150405Z")
+	scope := date + "/" + region + "/s3/aws4_request"
+
+	signed := url.Values{}
+	for name, values := range query {
+		signed[name] = values
+	}
+	signed.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	signed.Set("X-Amz-Credential", accessKey+"/"+scope)
+	signed.Set("X-Amz-Date", timestamp)
+	signed.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	signed.Set("X-Amz-SignedHeaders", "host")
+	if sessionToken != "" {
+		signed.Set("X-Amz-Security-Token", sessionToken)
+	}
+	canonicalQuery := awsCanonicalQuery(signed)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalPath,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return scheme + "://" + host + canonicalPath + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the message under the key.
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalQuery encodes query parameters sorted by name, as Signature Version 4 requires.
+func awsCanonicalQuery(query url.Values) string {
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		values := slices.Clone(query[name])
+		slices.Sort(values)
+		for _, value := range values {
+			pairs = append(pairs, awsURIEncode(name, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes everything but the unreserved characters (and "/" in paths),
+// which is stricter than net/url and what Signature Version 4 expects.
+func awsURIEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for index := 0; index < len(value); index++ {
+		character := value[index]
+		switch {
+		case 'A' <= character && character <= 'Z', 'a' <= character && character <= 'z', '0' <= character && character <= '9',
+			character == '-', character == '_', character == '.', character == '~':
+			encoded.WriteByte(character)
+		case character == '/' && !encodeSlash:
+			encoded.WriteByte(character)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", character)
+		}
+	}
+	return encoded.String()
+}
+
+// do sends a presigned request and returns the response if its status is one of the expected ones.
+// A 404 is reported as fs.ErrNotExist.
+func (storage *s3Storage) do(method, key string, query url.Values, body io.Reader, size int64, expected ...int) (*http.Response, error) {
+	request, err := http.NewRequest(method, storage.objectURL(method, key, query), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.ContentLength = size
+	}
+	response, err := storage.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(expected, response.StatusCode) {
+		return response, nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound && method != http.MethodPost {
+		return nil, fmt.Errorf("s3://%s/%s: %w", storage.bucket, key, fs.ErrNotExist)
+	}
+	detail, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return nil, fmt.Errorf("%s s3://%s/%s: %s %s", method, storage.bucket, key, response.Status, strings.TrimSpace(string(detail)))
+}
+
+func (storage *s3Storage) put(name string, contents io.Reader, size int64, modified time.Time) error {
+	if !validStorageName(name) {
+		return fmt.Errorf("invalid storage name %q", name)
+	}
+	upload := &s3Upload{key: storage.prefix + name}
+	if size >= 0 && size <= s3PartSize {
+		// Small files wait on disk until commit
+		spool, err := os.CreateTemp("", "archiver-s3-*")
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(spool, contents)
+		if closeErr := spool.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil && written != size {
+			err = fmt.Errorf("read %d bytes of %s, expected %d", written, name, size)
+		}
+		if err != nil {
+			os.Remove(spool.Name())
+			return err
+		}
+		upload.spoolPath = spool.Name()
+		storage.staged = append(storage.staged, upload)
+		return nil
+	}
+
+	// Large files go up in parts now; the object only appears when the upload is completed
+	response, err := storage.do(http.MethodPost, upload.key, url.Values{"uploads": {""}}, nil, 0, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var initiated s3InitiateMultipartUploadResult
+	err = xml.NewDecoder(response.Body).Decode(&initiated)
+	response.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("starting multipart upload of %s: no upload ID (%v)", upload.key, err)
+	}
+	upload.uploadID = initiated.UploadID
+	storage.staged = append(storage.staged, upload)
+
+	buffer := make([]byte, s3PartSize)
+	for partNumber := 1; ; partNumber++ {
+		read, err := io.ReadFull(contents, buffer)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		if read == 0 && partNumber > 1 {
+			break
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {upload.uploadID}}
+		response, err := storage.do(http.MethodPut, upload.key, query, bytes.NewReader(buffer[:read]), int64(read), http.StatusOK)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		upload.parts = append(upload.parts, s3CompletedPart{PartNumber: partNumber, ETag: response.Header.Get("ETag")})
+		if read < s3PartSize {
+			break
+		}
+	}
+	return nil
+}
+
+func (storage *s3Storage) stat(name string) (storedFile, error) {
+	response, err := storage.do(http.MethodHead, storage.prefix+name, nil, nil, 0, http.StatusOK)
+	if err != nil {
+		return storedFile{}, err
+	}
+	response.Body.Close()
+	modified, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return storedFile{Name: name, Size: response.ContentLength, Modified: modified}, nil
+}
+
+func (storage *s3Storage) list(prefix string) ([]storedFile, error) {
+	var files []storedFile
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {storage.prefix + prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := storage.do(http.MethodGet, "", query, nil, 0, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		var page s3ListBucketResult
+		err = xml.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %v", storage.bucket, storage.prefix+prefix, err)
+		}
+		for _, object := range page.Contents {
+			files = append(files, storedFile{
+				Name:     strings.TrimPrefix(object.Key, storage.prefix),
+				Size:     object.Size,
+				Modified: object.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return files, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+func (storage *s3Storage) open(name string) (io.ReadCloser, error) {
+	response, err := storage.do(http.MethodGet, storage.prefix+name, nil, nil, 0, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// commit sends the spooled files and completes the multipart uploads. S3 has no multi-object
+// transaction, but every object appears whole or not at all.
+func (storage *s3Storage) commit() error {
+	for len(storage.staged) > 0 {
+		upload := storage.staged[0]
+		if upload.spoolPath != "" {
+			spool, err := os.Open(upload.spoolPath)
+			if err != nil {
+				return err
+			}
+			info, err := spool.Stat()
+			if err != nil {
+				spool.Close()
+				return err
+			}
+			response, err := storage.do(http.MethodPut, upload.key, nil, spool, info.Size(), http.StatusOK)
+			spool.Close()
+			if err != nil {
+				return err
+			}
+			response.Body.Close()
+			os.Remove(upload.spoolPath)
+		} else {
+			body, err := xml.Marshal(s3CompleteMultipartUpload{Parts: upload.parts})
+			if err != nil {
+				return err
+			}
+			response, err := storage.do(http.MethodPost, upload.key, url.Values{"uploadId": {upload.uploadID}},
+				bytes.NewReader(body), int64(len(body)), http.StatusOK)
+			if err != nil {
+				return err
+			}
+			// S3 can report a failed completion inside a 200 response
+			result, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+			response.Body.Close()
+			if bytes.Contains(result, []byte("<Error>")) {
+				return fmt.Errorf("completing upload of %s: %s", upload.key, strings.TrimSpace(string(result)))
+			}
+		}
+		storage.staged = storage.staged[1:]
+	}
+	return nil
+}
+
+// abort removes the spooled files and cancels the multipart uploads so their parts are not billed.
+func (storage *s3Storage) abort() error {
+	var firstErr error
+	for _, upload := range storage.staged {
+		if upload.spoolPath != "" {
+			os.Remove(upload.spoolPath)
+			continue
+		}
+		response, err := storage.do(http.MethodDelete, upload.key, url.Values{"uploadId": {upload.uploadID}}, nil, 0,
+			http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		response.Body.Close()
+	}
+	storage.staged = nil
+	return firstErr
+}
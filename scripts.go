@@ -0,0 +1,207 @@
+package main // Define the main package
+
+import (
+	"encoding/json" // Provides JSON decoding for embedded data blocks
+	"maps"          // Provides map iteration helpers
+	"regexp"        // Provides regex support functions.
+	"slices"        // Provides slice sorting helpers
+	"strconv"       // Provides string unquoting for JavaScript escapes
+	"strings"       // Provides string manipulation functions
+
+	"golang.org/x/net/html" // Provides HTML parsing functions
+)
+
+// scriptStringLiteralRegex matches double- and single-quoted JavaScript string literals.
+var scriptStringLiteralRegex = regexp.MustCompile(`"((?:[^"\\\n]|\\.)*)"|'((?:[^'\\\n]|\\.)*)'`)
+
+// embeddedURLRegex matches absolute or protocol-relative URLs inside a larger string (e.g. HTML stored in JSON).
+var embeddedURLRegex = regexp.MustCompile(`(?:https?:)?//[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}[^\s"'<>\\]*`)
+
+// extractScriptURLs takes HTML content as a string and returns all asset URLs it finds inside <script> blocks,
+// resolved against the page URL. JSON blocks are decoded and every string value is checked;
+// inline JavaScript is scanned for string literals.
+func extractScriptURLs(htmlContent, pageURL string) []discoveredLink {
+	// Try parsing the HTML content into a document tree
+	document, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		// If parsing fails, just return an empty slice
+		return []discoveredLink{}
+	}
+
+	// Relative links resolve against the page URL or its <base href>
+	baseURL := documentBaseURL(document, pageURL)
+
+	// Slice to store all found script URLs
+	var scriptURLs []discoveredLink
+
+	// The closest heading seen so far in document order
+	currentHeading := ""
+
+	// Recursive function to walk through each HTML node
+	var traverse func(*html.Node)
+	traverse = func(node *html.Node) {
+		// Remember the latest heading for the scripts that follow it
+		if isHeadingNode(node) {
+			currentHeading = nodeText(node)
+		}
+		// Check if the current node is a <script> tag
+		if node.Type == html.ElementNode && node.Data == "script" {
+			// Look up the script type (empty means JavaScript)
+			scriptType := ""
+			for _, attribute := range node.Attr {
+				if attribute.Key == "type" {
+					scriptType = strings.ToLower(strings.TrimSpace(attribute.Val))
+				}
+			}
+			// Collect the raw script body from its text children
+			var body strings.Builder
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				if child.Type == html.TextNode {
+					body.WriteString(child.Data)
+				}
+			}
+			for _, scriptURL := range extractURLsFromScript(scriptType, body.String()) {
+				// Keep only resolvable URLs that classify as an asset
+				resolved := resolveLinkURL(baseURL, scriptURL.URL)
+				if asset := classifyAssetURL(resolved); resolved != "" && asset != nil {
+					scriptURL.URL = resolved
+					scriptURL.AssetType = asset.Name
+					scriptURL.Context.Page = pageURL
+					scriptURL.Context.Heading = currentHeading
+					scriptURL.Context.Section = sectionOf(node)
+					scriptURLs = append(scriptURLs, scriptURL)
+				}
+			}
+		}
+		// Recursively check all child nodes
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			traverse(child)
+		}
+	}
+
+	// Start traversing from the root of the document
+	traverse(document)
+
+	// Return the collected script URLs
+	return scriptURLs
+}
+
+// extractURLsFromScript returns the URLs found in a single script body of the given type.
+// URLs inside Shopify product data carry the product title in their context.
+func extractURLsFromScript(scriptType, body string) []discoveredLink {
+	var foundURLs []discoveredLink
+
+	// JSON blocks (application/json, application/ld+json) are decoded and walked
+	if strings.Contains(scriptType, "json") {
+		var value any
+		if err := json.Unmarshal([]byte(body), &value); err == nil {
+			walkJSONStrings(value, "", func(text, product string) {
+				for _, foundURL := range urlsFromScriptString(text) {
+					foundURLs = append(foundURLs, discoveredLink{URL: foundURL, Context: linkContext{Product: product}})
+				}
+			})
+			return foundURLs
+		}
+		// Malformed JSON falls through to the literal scanner below
+	} else if scriptType != "" && !strings.Contains(scriptType, "javascript") && scriptType != "module" {
+		// Skip templates and other non-script payloads
+		return foundURLs
+	}
+
+	// Scan inline JavaScript for quoted string literals
+	for _, match := range scriptStringLiteralRegex.FindAllStringSubmatch(body, -1) {
+		literal := match[1]
+		if literal == "" {
+			literal = match[2]
+		}
+		for _, foundURL := range urlsFromScriptString(unescapeScriptString(literal)) {
+			foundURLs = append(foundURLs, discoveredLink{URL: foundURL})
+		}
+	}
+	return foundURLs
+}
+
+// walkJSONStrings calls visit for every string value nested anywhere inside a decoded JSON value,
+// together with the title of the closest enclosing Shopify product object ("" outside products).
+func walkJSONStrings(value any, product string, visit func(text, product string)) {
+	switch typed := value.(type) {
+	case string:
+		visit(typed, product)
+	case []any:
+		for _, item := range typed {
+			walkJSONStrings(item, product, visit)
+		}
+	case map[string]any:
+		// Objects describing a product name everything nested inside them
+		if title := shopifyProductTitle(typed); title != "" {
+			product = title
+		}
+		// Visit keys in sorted order so the discovered URL order is stable between runs
+		for _, key := range slices.Sorted(maps.Keys(typed)) {
+			walkJSONStrings(typed[key], product, visit)
+		}
+	}
+}
+
+// shopifyProductTitle returns the product title if the JSON object is a Shopify product
+// (an object with "handle" and "title") or a schema.org Product ("@type": "Product" with "name").
+func shopifyProductTitle(object map[string]any) string {
+	if _, hasHandle := object["handle"].(string); hasHandle {
+		if title, ok := object["title"].(string); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	if objectType, _ := object["@type"].(string); objectType == "Product" {
+		if name, ok := object["name"].(string); ok {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// urlsFromScriptString returns the string itself if it looks like a URL,
+// otherwise any absolute URLs embedded inside it.
+func urlsFromScriptString(text string) []string {
+	// Shopify escapes slashes as "\/\/cdn.shopify.com" even inside decoded strings
+	text = strings.TrimSpace(strings.ReplaceAll(text, `\/`, "/"))
+	if looksLikeURL(text) {
+		return []string{text}
+	}
+	var foundURLs []string
+	for _, match := range embeddedURLRegex.FindAllString(text, -1) {
+		foundURLs = append(foundURLs, match)
+	}
+	return foundURLs
+}
+
+// looksLikeURL reports whether the whole string is an absolute, protocol-relative or root-relative URL.
+func looksLikeURL(text string) bool {
+	if text == "" || strings.ContainsAny(text, " \t\n<>\"'") {
+		return false
+	}
+	lowerText := strings.ToLower(text)
+	switch {
+	case strings.HasPrefix(lowerText, "http://"), strings.HasPrefix(lowerText, "https://"):
+		return true
+	case strings.HasPrefix(lowerText, "//"):
+		return len(lowerText) > 2
+	case strings.HasPrefix(lowerText, "/"):
+		// Root-relative paths only count when they point at a file
+		return getFileExtension(getFileNameOnly(text)) != ""
+	}
+	return false
+}
+
+// unescapeScriptString resolves JavaScript escapes such as "\/" and "\u002F" in a string literal body.
+func unescapeScriptString(literal string) string {
+	// Go's unquote does not know "\/", so resolve it first
+	literal = strings.ReplaceAll(literal, `\/`, "/")
+	if !strings.Contains(literal, `\`) {
+		return literal
+	}
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(literal, `"`, `\"`) + `"`)
+	if err != nil {
+		return literal // Leave unknown escape sequences untouched
+	}
+	return unquoted
+}
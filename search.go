@@ -0,0 +1,476 @@
+package main // Define the main package
+
+import (
+	"encoding/json" // Provides JSON encoding for the index file
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output for search results
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"math"          // Provides the logarithm for BM25
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+	"unicode"       // Provides character classes for tokenizing
+)
+
+// searchIndexPath is where the full-text index of the archived PDFs is stored, next to the manifest.
+const searchIndexPath = "search-index.json"
+
+// BM25 parameters (the usual defaults).
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRadius is how many characters of context are shown on each side of the first match.
+const snippetRadius = 80
+
+// searchIndex is an inverted index over every page of every archived PDF.
+type searchIndex struct {
+	Files         map[string]string          `json:"files"`          // Indexed path → SHA-256 of the indexed version
+	Documents     []searchDocument           `json:"documents"`      // One document per page
+	Postings      map[string][]searchPosting `json:"postings"`       // Term → pages containing it
+	AverageLength float64                    `json:"average_length"` // Mean page length in terms
+}
+
+// searchDocument is one page of an archived PDF, or the file listing of a ZIP or RAR archive.
+type searchDocument struct {
+	Path   string `json:"path"`   // Slash-separated path of the PDF or archive
+	Page   int    `json:"page"`   // 1-based page number; 0 for an archive's file listing
+	Length int    `json:"length"` // Number of terms on the page
+	Text   string `json:"text"`   // Extracted text, kept for snippets
+}
+
+// searchPosting is a (document index, term frequency) pair, stored as a two-element array.
+type searchPosting [2]int
+
+// searchToken is a term with its byte span in the original text.
+type searchToken struct {
+	Term  string
+	Start int
+	End   int
+	Part  bool // Part of a compound term, indexed in addition to the compound
+}
+
+// searchResult is one ranked page.
+type searchResult struct {
+	Document *searchDocument
+	Score    float64
+}
+
+// runSearch implements the search command: refresh the index if PDFs or archives changed, then print the best pages.
+func runSearch(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := flags.Int("n", 10, "maximum number of results")
+	indexFile := flags.String("index", searchIndexPath, "index file to read and refresh")
+	flags.Parse(args)
+	query := strings.Join(flags.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fmt.Fprintln(os.Stderr, `Usage: search [-n 10] "<query>"`)
+		return 2
+	}
+
+	// Make sure the index covers the current archive
+	index := loadSearchIndex(*indexFile)
+	if updateSearchIndex(index, loadManifest(manifestPath)) {
+		saveSearchIndex(*indexFile, index)
+	}
+
+	results := index.search(query, *limit)
+	if len(results) == 0 {
+		fmt.Printf("No pages match %q\n", query)
+		return 1
+	}
+	terms := queryTerms(query)
+	highlight := markdownHighlight
+	if stat, err := os.Stdout.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		highlight = terminalHighlight // Bold yellow on a terminal
+	}
+	for rank, result := range results {
+		location := fmt.Sprintf("page %d", result.Document.Page)
+		if result.Document.Page == 0 {
+			location = "file listing"
+		}
+		fmt.Printf("%d. %s (%s, score %.2f)\n", rank+1, result.Document.Path, location, result.Score)
+		fmt.Printf("   %s\n", searchSnippet(result.Document.Text, terms, highlight))
+	}
+	return 0
+}
+
+// loadSearchIndex reads the index from disk. A missing or unreadable index yields an empty one.
+func loadSearchIndex(indexFile string) *searchIndex {
+	loaded := &searchIndex{Files: make(map[string]string), Postings: make(map[string][]searchPosting)}
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read search index %s: %v", indexFile, err)
+		}
+		return loaded
+	}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		log.Printf("Failed to parse search index %s: %v", indexFile, err)
+		return &searchIndex{Files: make(map[string]string), Postings: make(map[string][]searchPosting)}
+	}
+	if loaded.Files == nil {
+		loaded.Files = make(map[string]string)
+	}
+	if loaded.Postings == nil {
+		loaded.Postings = make(map[string][]searchPosting)
+	}
+	return loaded
+}
+
+// saveSearchIndex writes the index through a temporary file, like the manifest.
+func saveSearchIndex(indexFile string, index *searchIndex) bool {
+	data, err := json.Marshal(index)
+	if err != nil {
+		log.Printf("Failed to encode search index: %v", err)
+		return false
+	}
+	temporaryFile := indexFile + ".tmp"
+	if err := os.WriteFile(temporaryFile, append(data, '\n'), 0o644); err != nil {
+		log.Printf("Failed to write search index %s: %v", temporaryFile, err)
+		return false
+	}
+	if err := os.Rename(temporaryFile, indexFile); err != nil {
+		log.Printf("Failed to replace search index %s: %v", indexFile, err)
+		return false
+	}
+	return true
+}
+
+// updateSearchIndex re-extracts PDFs and archive listings whose hash changed, drops removed ones and rebuilds the postings.
+// It reports whether anything changed.
+func updateSearchIndex(index *searchIndex, archive *archiveManifest) bool {
+	// Group the existing pages by file so unchanged files keep their text
+	pagesByFile := make(map[string][]searchDocument)
+	for _, document := range index.Documents {
+		pagesByFile[document.Path] = append(pagesByFile[document.Path], document)
+	}
+
+	changed := false
+	current := make(map[string]bool)
+	var paths []string
+	for _, assetName := range []string{"pdf", "zip", "rar"} {
+		paths = append(paths, archiveFilesOfType(archive, assetName)...)
+	}
+	for _, path := range paths {
+		current[path] = true
+		// Prefer the manifest hash; hash files the manifest does not know yet
+		sum := ""
+		if entry, found := archive.Entries[path]; found {
+			sum = entry.SHA256
+		}
+		if sum == "" {
+			if _, fileSum, err := hashFile(filepath.FromSlash(path)); err == nil {
+				sum = fileSum
+			}
+		}
+		if indexed, found := index.Files[path]; found && indexed == sum {
+			continue
+		}
+		if asset := assetTypeByExtension(filepath.Ext(path)); asset != nil && archiveWalkers[asset.Name] != nil {
+			pagesByFile[path] = archiveListingPages(archive, path, asset.Name)
+		} else {
+			pagesByFile[path] = extractSearchPages(path)
+		}
+		index.Files[path] = sum
+		changed = true
+	}
+	for path := range index.Files {
+		if !current[path] {
+			delete(index.Files, path)
+			delete(pagesByFile, path)
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	// Rebuild the documents and postings in a stable order
+	index.Documents = index.Documents[:0]
+	for _, path := range slices.Sorted(maps.Keys(pagesByFile)) {
+		index.Documents = append(index.Documents, pagesByFile[path]...)
+	}
+	index.Postings = make(map[string][]searchPosting)
+	totalLength := 0
+	for documentIndex := range index.Documents {
+		frequencies := make(map[string]int)
+		tokens := tokenizeSearchText(index.Documents[documentIndex].Text)
+		for _, token := range tokens {
+			frequencies[token.Term]++
+		}
+		index.Documents[documentIndex].Length = len(tokens)
+		totalLength += len(tokens)
+		for term, frequency := range frequencies {
+			index.Postings[term] = append(index.Postings[term], searchPosting{documentIndex, frequency})
+		}
+	}
+	index.AverageLength = 0
+	if len(index.Documents) > 0 {
+		index.AverageLength = float64(totalLength) / float64(len(index.Documents))
+	}
+	pdfCount := 0
+	for path := range index.Files {
+		if asset := assetTypeByExtension(filepath.Ext(path)); asset != nil && asset.Name == "pdf" {
+			pdfCount++
+		}
+	}
+	log.Printf("Indexed %d pages from %d PDFs and %d archive listings", len(index.Documents), pdfCount, len(index.Files)-pdfCount)
+	return true
+}
+
+// extractSearchPages extracts the text of every page of a PDF. Pages without text are skipped.
+func extractSearchPages(path string) []searchDocument {
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		log.Printf("Failed to read %s: %v", path, err)
+		return nil
+	}
+	document, err := openPDF(data)
+	if err != nil {
+		log.Printf("Failed to parse %s: %v", path, err)
+		return nil
+	}
+	texts, err := document.extractPageText()
+	if err != nil {
+		log.Printf("Incomplete page tree in %s: %v", path, err)
+	}
+	var pages []searchDocument
+	for number, text := range texts {
+		if text != "" {
+			pages = append(pages, searchDocument{Path: path, Page: number + 1, Text: text})
+		}
+	}
+	if len(pages) == 0 {
+		log.Printf("No text found in %s (text may be converted to outlines)", path)
+	}
+	return pages
+}
+
+// archiveListingPages returns the file listing of a ZIP or RAR archive as one document, so the
+// files inside can be found by name. The manifest's listing is used when it has one.
+func archiveListingPages(archive *archiveManifest, path, assetName string) []searchDocument {
+	var members []archiveMember
+	if entry, found := archive.Entries[path]; found && entry.Contents != nil {
+		members = entry.Contents
+	} else {
+		listed, err := listArchive(filepath.FromSlash(path), assetName)
+		if err != nil {
+			log.Printf("Failed to list %s: %v", path, err)
+			return nil
+		}
+		members = listed
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	names := make([]string, len(members))
+	for index, member := range members {
+		names[index] = member.Path
+	}
+	return []searchDocument{{Path: path, Page: 0, Text: strings.Join(names, "\n")}}
+}
+
+// tokenizeSearchText splits text into lowercase terms. Letters and digits joined by ".", "-" or "_"
+// form one compound term (e.g. "38.43.4", "avatar_gnd") that is also indexed by its parts.
+// Each CJK character is a term of its own, since those scripts do not separate words with spaces.
+func tokenizeSearchText(text string) []searchToken {
+	var tokens []searchToken
+	start := -1 // Start of the current compound term
+	partStart := -1
+	var parts []searchToken
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if partStart >= 0 {
+			parts = append(parts, searchToken{Term: strings.ToLower(text[partStart:end]), Start: partStart, End: end})
+		}
+		tokens = append(tokens, searchToken{Term: strings.ToLower(text[start:end]), Start: start, End: end})
+		if len(parts) > 1 {
+			for _, part := range parts {
+				part.Part = true
+				tokens = append(tokens, part)
+			}
+		}
+		start, partStart, parts = -1, -1, parts[:0]
+	}
+	for position, character := range text {
+		switch {
+		case isCJK(character):
+			flush(position)
+			end := position + len(string(character))
+			tokens = append(tokens, searchToken{Term: string(character), Start: position, End: end})
+		case unicode.IsLetter(character) || unicode.IsDigit(character):
+			if start < 0 {
+				start = position
+			}
+			if partStart < 0 {
+				partStart = position
+			}
+		case start >= 0 && (character == '.' || character == '-' || character == '_') && joinsSearchTerm(text, position):
+			// Close the current part but keep the compound term going
+			parts = append(parts, searchToken{Term: strings.ToLower(text[partStart:position]), Start: partStart, End: position})
+			partStart = -1
+		default:
+			flush(position)
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// joinsSearchTerm reports whether the separator at position is followed by a letter or digit.
+func joinsSearchTerm(text string, position int) bool {
+	for _, next := range text[position+1:] {
+		return (unicode.IsLetter(next) || unicode.IsDigit(next)) && !isCJK(next)
+	}
+	return false
+}
+
+// isCJK reports whether a character belongs to a script written without spaces between words.
+func isCJK(character rune) bool {
+	return unicode.In(character, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// queryTerms returns the distinct terms of a query. Compound terms are searched as a whole,
+// so "38.43.4" does not also match every page mentioning "4".
+func queryTerms(query string) []string {
+	var terms []string
+	for _, token := range tokenizeSearchText(normalizePDFText(query)) {
+		if !token.Part && !slices.Contains(terms, token.Term) {
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// search ranks the pages against the query with BM25 and returns the best ones.
+func (index *searchIndex) search(query string, limit int) []searchResult {
+	documentCount := float64(len(index.Documents))
+	scores := make(map[int]float64)
+	for _, term := range queryTerms(query) {
+		postings := index.Postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		// Rare terms weigh more than terms found on most pages
+		frequency := float64(len(postings))
+		inverse := math.Log(1 + (documentCount-frequency+0.5)/(frequency+0.5))
+		for _, posting := range postings {
+			if posting[0] >= len(index.Documents) {
+				continue // Stale postings from a hand-edited index
+			}
+			termFrequency := float64(posting[1])
+			length := float64(index.Documents[posting[0]].Length)
+			normalization := 1 - bm25B + bm25B*length/max(index.AverageLength, 1)
+			scores[posting[0]] += inverse * termFrequency * (bm25K1 + 1) / (termFrequency + bm25K1*normalization)
+		}
+	}
+
+	results := make([]searchResult, 0, len(scores))
+	for documentIndex, score := range scores {
+		results = append(results, searchResult{Document: &index.Documents[documentIndex], Score: score})
+	}
+	// Highest score first; ties keep path and page order
+	slices.SortFunc(results, func(left, right searchResult) int {
+		if left.Score != right.Score {
+			if left.Score > right.Score {
+				return -1
+			}
+			return 1
+		}
+		if byPath := strings.Compare(left.Document.Path, right.Document.Path); byPath != 0 {
+			return byPath
+		}
+		return left.Document.Page - right.Document.Page
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// markdownHighlight marks a match for non-terminal output.
+func markdownHighlight(match string) string {
+	return "**" + match + "**"
+}
+
+// terminalHighlight marks a match in bold yellow.
+func terminalHighlight(match string) string {
+	return "\x1b[1;33m" + match + "\x1b[0m"
+}
+
+// searchSnippet returns one line of text around the first match with every matching term highlighted.
+func searchSnippet(text string, terms []string, highlight func(string) string) string {
+	tokens := tokenizeSearchText(text)
+	var matches []searchToken
+	for _, token := range tokens {
+		if slices.Contains(terms, token.Term) {
+			matches = append(matches, token)
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	// Window of snippetRadius characters on each side of the first match
+	start, end := matches[0].Start, matches[0].End
+	for count := 0; count < snippetRadius && start > 0; count++ {
+		start = previousRuneStart(text, start)
+	}
+	for count := 0; count < snippetRadius && end < len(text); count++ {
+		end = nextRuneEnd(text, end)
+	}
+
+	// Merge touching matches (e.g. consecutive CJK characters) into one highlighted span;
+	// parts inside an already highlighted compound are skipped
+	var spans []searchToken
+	for _, match := range matches {
+		if match.Start < start || match.End > end {
+			continue
+		}
+		if last := len(spans) - 1; last >= 0 && match.Start <= spans[last].End {
+			spans[last].End = max(spans[last].End, match.End)
+			continue
+		}
+		spans = append(spans, match)
+	}
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+	position := start
+	for _, span := range spans {
+		snippet.WriteString(text[position:span.Start])
+		snippet.WriteString(highlight(text[span.Start:span.End]))
+		position = span.End
+	}
+	snippet.WriteString(text[position:end])
+	if end < len(text) {
+		snippet.WriteString("…")
+	}
+	return strings.Join(strings.Fields(snippet.String()), " ")
+}
+
+// previousRuneStart returns the byte offset of the character before position.
+func previousRuneStart(text string, position int) int {
+	position--
+	for position > 0 && text[position]&0xC0 == 0x80 {
+		position--
+	}
+	return position
+}
+
+// nextRuneEnd returns the byte offset after the character at position.
+func nextRuneEnd(text string, position int) int {
+	position++
+	for position < len(text) && text[position]&0xC0 == 0x80 {
+		position++
+	}
+	return position
+}
@@ -0,0 +1,175 @@
+package main // Define the main package
+
+import (
+	"os"            // Provides file writing for the test archive
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice comparison
+	"strings"       // Provides string helpers
+	"testing"       // Provides the test runner
+)
+
+// TestTokenizeSearchText checks compound terms and their parts, CJK characters and the token spans.
+func TestTokenizeSearchText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string // Terms in order; parts of a compound follow the compound
+	}{
+		{"words", "Connect the Camera", []string{"connect", "the", "camera"}},
+		{"version number", "Firmware 38.43.4 released", []string{"firmware", "38.43.4", "38", "43", "4", "released"}},
+		{"underscore and hyphen", "avatar_GND wi-fi", []string{"avatar_gnd", "avatar", "gnd", "wi-fi", "wi", "fi"}},
+		{"trailing separator", "see v1.2. now", []string{"see", "v1.2", "v1", "2", "now"}},
+		{"separator without a following letter", "a - b", []string{"a", "b"}},
+		{"cjk characters", "图传ABC模块", []string{"图", "传", "abc", "模", "块"}},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var terms []string
+			for _, token := range tokenizeSearchText(test.text) {
+				terms = append(terms, token.Term)
+				if span := strings.ToLower(test.text[token.Start:token.End]); span != token.Term {
+					t.Errorf("token %q spans %q", token.Term, span)
+				}
+			}
+			if !slices.Equal(terms, test.expected) {
+				t.Errorf("tokenizeSearchText(%q) = %q, want %q", test.text, terms, test.expected)
+			}
+		})
+	}
+}
+
+// TestQueryTerms checks that queries search compound terms as a whole and drop repeated terms.
+func TestQueryTerms(t *testing.T) {
+	if actual := queryTerms("Firmware 38.43.4 firmware"); !slices.Equal(actual, []string{"firmware", "38.43.4"}) {
+		t.Errorf("queryTerms = %q, want [firmware 38.43.4]", actual)
+	}
+}
+
+// TestSearchSnippet checks the highlighting, the merging of touching matches and the context window.
+func TestSearchSnippet(t *testing.T) {
+	long := strings.Repeat("x", 200) + " target " + strings.Repeat("y", 200)
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		expected string
+	}{
+		{"one match", "Connect the Avatar_GND to power", []string{"avatar_gnd"}, "Connect the **Avatar_GND** to power"},
+		{"every match is highlighted", "The camera and the goggles", []string{"the"}, "**The** camera and **the** goggles"},
+		{"part inside a highlighted compound", "Connect the Avatar_GND", []string{"avatar_gnd", "gnd"}, "Connect the **Avatar_GND**"},
+		{"touching cjk matches", "请使用图传模块", []string{"图", "传"}, "请使用**图传**模块"},
+		{"whitespace is collapsed", "line one\n\n  target   here", []string{"target"}, "line one **target** here"},
+		{"context window", long, []string{"target"}, "…" + strings.Repeat("x", 79) + " **target** " + strings.Repeat("y", 79) + "…"},
+		{"no match", "Connect the camera", []string{"goggles"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := searchSnippet(test.text, test.terms, markdownHighlight); actual != test.expected {
+				t.Errorf("searchSnippet = %q, want %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+// testSearchArchive changes into an empty directory holding one ZIP per listing and returns a manifest
+// that lists their contents, so the index can be built without PDFs.
+func testSearchArchive(t *testing.T, listings map[string][]string) *archiveManifest {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("ZIPs", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	archive := &archiveManifest{Entries: make(map[string]*manifestEntry)}
+	for name, members := range listings {
+		key := "ZIPs/" + name
+		if err := os.WriteFile(filepath.FromSlash(key), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		entry := &manifestEntry{Path: key, AssetType: "zip", SHA256: name}
+		for _, member := range members {
+			entry.Contents = append(entry.Contents, archiveMember{Path: member})
+		}
+		archive.Entries[key] = entry
+	}
+	return archive
+}
+
+// TestUpdateSearchIndex checks that the index is built from the archive listings, left alone when
+// nothing changed and updated when a file is replaced or removed.
+func TestUpdateSearchIndex(t *testing.T) {
+	archive := testSearchArchive(t, map[string][]string{
+		"ratel.zip":  {"ratel manual.pdf", "ratel case.stl"},
+		"nebula.zip": {"nebula manual.pdf"},
+	})
+	index := &searchIndex{Files: make(map[string]string), Postings: make(map[string][]searchPosting)}
+	if !updateSearchIndex(index, archive) {
+		t.Fatal("first update reported no change")
+	}
+	if len(index.Documents) != 2 || index.Documents[0].Path != "ZIPs/nebula.zip" || index.Documents[1].Path != "ZIPs/ratel.zip" {
+		t.Fatalf("documents = %+v, want one listing per archive in path order", index.Documents)
+	}
+	if index.Documents[1].Length != 8 || index.AverageLength != 6 {
+		t.Errorf("ratel length = %d, average = %v; want 8 and 6", index.Documents[1].Length, index.AverageLength)
+	}
+	if postings := index.Postings["manual"]; !slices.Equal(postings, []searchPosting{{0, 1}, {1, 1}}) {
+		t.Errorf("postings for manual = %v, want both listings once", postings)
+	}
+	if updateSearchIndex(index, archive) {
+		t.Error("update without changes reported a change")
+	}
+
+	// A new version of one archive replaces its listing, a removed archive drops out
+	archive.Entries["ZIPs/ratel.zip"].SHA256 = "changed"
+	archive.Entries["ZIPs/ratel.zip"].Contents = []archiveMember{{Path: "ratel 2 manual.pdf"}}
+	if err := os.Remove(filepath.FromSlash("ZIPs/nebula.zip")); err != nil {
+		t.Fatal(err)
+	}
+	if !updateSearchIndex(index, archive) {
+		t.Fatal("update after changes reported no change")
+	}
+	if len(index.Documents) != 1 || index.Documents[0].Text != "ratel 2 manual.pdf" || len(index.Files) != 1 {
+		t.Errorf("documents = %+v, files = %v; want only the new ratel listing", index.Documents, index.Files)
+	}
+	if _, found := index.Postings["nebula"]; found {
+		t.Error("postings still list the removed archive")
+	}
+}
+
+// TestSearch checks the BM25 ranking: pages matching more and rarer query terms rank first.
+func TestSearch(t *testing.T) {
+	archive := testSearchArchive(t, map[string][]string{
+		"a.zip": {"ratel manual.pdf", "ratel case.stl", "ratel lens.stl"},
+		"b.zip": {"nebula manual.pdf"},
+		"c.zip": {"firmware 38.43.4/readme.txt"},
+		"d.zip": {"board 4/readme.txt"},
+	})
+	index := &searchIndex{Files: make(map[string]string), Postings: make(map[string][]searchPosting)}
+	updateSearchIndex(index, archive)
+
+	tests := []struct {
+		query    string
+		limit    int
+		expected []string // Paths in rank order
+	}{
+		{"nebula", 0, []string{"ZIPs/b.zip"}},
+		{"ratel", 0, []string{"ZIPs/a.zip"}},
+		{"nebula manual", 0, []string{"ZIPs/b.zip", "ZIPs/a.zip"}},
+		{"manual", 0, []string{"ZIPs/b.zip", "ZIPs/a.zip"}}, // The shorter listing ranks first
+		{"manual", 1, []string{"ZIPs/b.zip"}},
+		{"38.43.4", 0, []string{"ZIPs/c.zip"}}, // Compound queries do not match their parts alone
+		{"4", 0, []string{"ZIPs/d.zip", "ZIPs/c.zip"}},
+		{"goggles", 0, nil},
+		{"", 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			var paths []string
+			for _, result := range index.search(test.query, test.limit) {
+				paths = append(paths, result.Document.Path)
+			}
+			if !slices.Equal(paths, test.expected) {
+				t.Errorf("search(%q, %d) = %q, want %q", test.query, test.limit, paths, test.expected)
+			}
+		})
+	}
+}
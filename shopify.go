@@ -0,0 +1,100 @@
+package main // Define the main package
+
+import (
+	"net/url" // Provides URL parsing and encoding
+	"path"    // Provides functions for manipulating slash-separated paths
+	"regexp"  // Provides regex support functions.
+	"strings" // Provides string manipulation functions
+)
+
+// shopifyTransformRegex matches the resize, crop, scale and format suffixes Shopify adds to an image file name
+// before its extension, e.g. "kit_100x100_crop_center@2x.progressive.jpg" or "logo_x800.png".
+var shopifyTransformRegex = regexp.MustCompile(`(?i)^(.+?)(?:_(?:\d+x\d*|x\d+))?(?:_crop_(?:top|center|bottom|left|right))?(?:@\d+x)?(?:\.progressive)?(\.[a-z0-9]+)$`)
+
+// shopifyTransformParameters are the image_url query parameters that ask the CDN for a transformed copy.
+// The "v" cache-busting version is kept; it does not change the image.
+var shopifyTransformParameters = []string{"width", "height", "crop", "format", "pad_color", "scale"}
+
+// isShopifyCDNURL reports whether the URL is served by the Shopify CDN, either directly
+// (cdn.shopify.com/s/files/...) or through a storefront's own domain (shop.example/cdn/shop/...).
+func isShopifyCDNURL(parsedURL *url.URL) bool {
+	host := strings.ToLower(parsedURL.Hostname())
+	return host == "cdn.shopify.com" || strings.HasPrefix(parsedURL.Path, "/cdn/shop/")
+}
+
+// normalizeShopifyURL strips the size, crop, scale and format transforms from a Shopify CDN URL so the
+// original upload is requested instead of a thumbnail. Other URLs are returned unchanged.
+func normalizeShopifyURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || !isShopifyCDNURL(parsedURL) {
+		return rawURL
+	}
+
+	// Strip the transforms from the file name
+	directory, fileName := path.Split(parsedURL.Path)
+	if match := shopifyTransformRegex.FindStringSubmatch(fileName); match != nil {
+		parsedURL.Path = directory + match[1] + match[2]
+		parsedURL.RawPath = ""
+	}
+
+	// Drop the transform parameters, keeping anything else in its original order
+	if parsedURL.RawQuery != "" {
+		var kept []string
+		for _, parameter := range strings.Split(parsedURL.RawQuery, "&") {
+			name, _, _ := strings.Cut(parameter, "=")
+			if decodedName, err := url.QueryUnescape(name); err == nil {
+				name = decodedName
+			}
+			if parameter == "" || containsFold(shopifyTransformParameters, name) {
+				continue
+			}
+			kept = append(kept, parameter)
+		}
+		parsedURL.RawQuery = strings.Join(kept, "&")
+	}
+	return parsedURL.String()
+}
+
+// containsFold reports whether the list contains the value, ignoring case.
+func containsFold(list []string, value string) bool {
+	for _, candidate := range list {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeShopifyLinks points every Shopify CDN image link at its original image. The URL as it appeared on
+// the page is kept in the link context for provenance, so the thumbnails a page used stay on record. Other
+// files are left alone: the CDN never resizes them, so a name like "kit_2x.zip" is the real name.
+func normalizeShopifyLinks(links []discoveredLink) []discoveredLink {
+	for index := range links {
+		link := &links[index]
+		if link.AssetType != "jpg" && link.AssetType != "png" {
+			continue
+		}
+		normalizedURL := normalizeShopifyURL(link.URL)
+		if normalizedURL == link.URL {
+			continue
+		}
+		// Stripping a format transform may change the file type; keep the old one if the new one is unknown
+		if asset := classifyAssetURL(normalizedURL); asset != nil {
+			link.AssetType = asset.Name
+		}
+		link.Context.LinkedURL = link.URL
+		link.URL = normalizedURL
+	}
+	return links
+}
+
+// linkedURLs returns the URLs the pages used for an asset when they differ from the URL it is fetched from.
+func linkedURLs(contexts []linkContext) []string {
+	var urls []string
+	for _, context := range contexts {
+		if context.LinkedURL != "" {
+			urls = append(urls, context.LinkedURL)
+		}
+	}
+	return removeDuplicatesFromSlice(urls)
+}
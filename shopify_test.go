@@ -0,0 +1,110 @@
+package main // Define the main package
+
+import (
+	"testing" // Provides the test runner
+)
+
+// TestNormalizeShopifyURL checks that resize, crop, scale and format transforms are stripped from
+// Shopify CDN URLs and that everything else is left alone.
+func TestNormalizeShopifyURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		expected string
+	}{
+		{
+			name:     "size, crop, scale and progressive suffixes",
+			rawURL:   "https://cdn.shopify.com/s/files/1/0/files/kit_100x100_crop_center@2x.progressive.jpg?v=123",
+			expected: "https://cdn.shopify.com/s/files/1/0/files/kit.jpg?v=123",
+		},
+		{
+			name:     "height-only suffix",
+			rawURL:   "https://cdn.shopify.com/s/files/1/0/files/logo_x800.png",
+			expected: "https://cdn.shopify.com/s/files/1/0/files/logo.png",
+		},
+		{
+			name:     "storefront CDN path with width suffix and parameter",
+			rawURL:   "https://caddxfpv.com/cdn/shop/files/ratel_1024x.png?v=5&width=600",
+			expected: "https://caddxfpv.com/cdn/shop/files/ratel.png?v=5",
+		},
+		{
+			name:     "every transform parameter, keeping the version",
+			rawURL:   "https://caddxfpv.com/cdn/shop/files/photo.jpg?width=300&height=200&crop=center&format=pjpg&v=9",
+			expected: "https://caddxfpv.com/cdn/shop/files/photo.jpg?v=9",
+		},
+		{
+			name:     "version numbers in document names are kept",
+			rawURL:   "https://caddxfpv.com/cdn/shop/files/manual_v1_2.pdf?v=1",
+			expected: "https://caddxfpv.com/cdn/shop/files/manual_v1_2.pdf?v=1",
+		},
+		{
+			name:     "untransformed upload",
+			rawURL:   "https://cdn.shopify.com/s/files/1/0/files/Goggles_X.PNG",
+			expected: "https://cdn.shopify.com/s/files/1/0/files/Goggles_X.PNG",
+		},
+		{
+			name:     "other hosts are not Shopify",
+			rawURL:   "https://example.com/images/kit_100x100.jpg",
+			expected: "https://example.com/images/kit_100x100.jpg",
+		},
+		{
+			name:     "unparseable URL",
+			rawURL:   "://bad",
+			expected: "://bad",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := normalizeShopifyURL(test.rawURL); actual != test.expected {
+				t.Errorf("normalizeShopifyURL(%q) = %q, want %q", test.rawURL, actual, test.expected)
+			}
+		})
+	}
+}
+
+// TestNormalizeShopifyLinks checks that only image links are pointed at the original upload, so other
+// files whose names end like a size suffix keep their real names.
+func TestNormalizeShopifyLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		link      discoveredLink
+		expected  string
+		assetType string
+	}{
+		{
+			name:      "image thumbnail",
+			link:      discoveredLink{URL: "https://cdn.shopify.com/s/files/1/0/files/kit_100x100.jpg?width=300", AssetType: "jpg"},
+			expected:  "https://cdn.shopify.com/s/files/1/0/files/kit.jpg",
+			assetType: "jpg",
+		},
+		{
+			name:      "format transform to a known type",
+			link:      discoveredLink{URL: "https://caddxfpv.com/cdn/shop/files/logo_x800.png?format=jpg", AssetType: "png"},
+			expected:  "https://caddxfpv.com/cdn/shop/files/logo.png",
+			assetType: "png",
+		},
+		{
+			name:      "archive named like a size variant",
+			link:      discoveredLink{URL: "https://cdn.shopify.com/s/files/1/0/files/kit_2x.zip?v=3", AssetType: "zip"},
+			expected:  "https://cdn.shopify.com/s/files/1/0/files/kit_2x.zip?v=3",
+			assetType: "zip",
+		},
+		{
+			name:      "document with a width parameter",
+			link:      discoveredLink{URL: "https://caddxfpv.com/cdn/shop/files/manual_1024x.pdf?width=600", AssetType: "pdf"},
+			expected:  "https://caddxfpv.com/cdn/shop/files/manual_1024x.pdf?width=600",
+			assetType: "pdf",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			links := normalizeShopifyLinks([]discoveredLink{test.link})
+			if links[0].URL != test.expected || links[0].AssetType != test.assetType {
+				t.Errorf("normalizeShopifyLinks = %s (%s), want %s (%s)", links[0].URL, links[0].AssetType, test.expected, test.assetType)
+			}
+			if changed := test.expected != test.link.URL; changed != (links[0].Context.LinkedURL == test.link.URL) {
+				t.Errorf("linked URL = %q, want the page's URL only when the link was rewritten", links[0].Context.LinkedURL)
+			}
+		})
+	}
+}
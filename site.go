@@ -0,0 +1,368 @@
+package main // Define the main package
+
+import (
+	"bytes"           // Provides buffers for thumbnails and rendered pages
+	"encoding/base64" // Provides base64 encoding for inline thumbnails
+	"flag"            // Provides command line flag parsing
+	"html/template"   // Provides safe HTML templating
+	"image"           // Provides image decoding
+	"image/color"     // Provides the thumbnail background colour
+	"image/jpeg"      // Registers and encodes JPEG images
+	_ "image/png"     // Registers the PNG decoder
+	"io"              // Provides seeking back to the start of the file
+	"log"             // Provides logging functions
+	"maps"            // Provides map iteration helpers
+	"os"              // Provides functions to interact with the OS (files, etc.)
+	"path"            // Provides functions for manipulating slash-separated paths
+	"path/filepath"   // Provides filepath manipulation functions
+	"slices"          // Provides slice sorting helpers
+	"time"            // Provides time-related functions
+)
+
+// defaultSiteDir is where the static site is written unless -out says otherwise.
+const defaultSiteDir = "site/"
+
+// thumbnailSize is the longest edge of the inline image thumbnails in pixels.
+const thumbnailSize = 120
+
+// siteFile is one archived file as shown on the site.
+type siteFile struct {
+	Name        string       // File name
+	Href        string       // Link from a site page to the archived file
+	URL         string       // Source URL
+	Size        int64        // Size in bytes
+	SHA256      string       // Hex SHA-256
+	FirstSeen   time.Time    // First run that found the file
+	Removed     bool         // No longer linked from the remote site
+	Thumbnail   template.URL // Inline data: URI for images and rendered models, empty otherwise
+	Product     string       // Product name from the catalog
+	ProductSlug string       // Product slug for links
+}
+
+// siteSection is one category of files on a product page.
+type siteSection struct {
+	Title string
+	Files []siteFile
+}
+
+// siteProduct is one product page.
+type siteProduct struct {
+	Name     string
+	Slug     string
+	Count    int
+	Sections []siteSection
+}
+
+// siteType is one per-type listing page.
+type siteType struct {
+	Name  string
+	Label string
+	Files []siteFile
+}
+
+// sitePage is the data passed to every page template.
+type sitePage struct {
+	Site     string // Display name of the archived vendor
+	Title    string
+	Products []*siteProduct
+	Types    []*siteType
+	Product  *siteProduct
+	Type     *siteType
+	Total    int
+	Removed  int
+	LastRun  time.Time
+}
+
+// siteTemplates renders the index, product and type pages. Styles are inline so the site has no external assets.
+var siteTemplates = template.Must(template.New("site").Funcs(template.FuncMap{
+	"size":  formatSize,
+	"short": func(hash string) string { return hash[:min(12, len(hash))] },
+	"date": func(moment time.Time) string {
+		if moment.IsZero() {
+			return "—"
+		}
+		return moment.Format("2006-01-02")
+	},
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} · {{.Site}} Documentation Backup</title>
+<style>
+body{font-family:system-ui,-apple-system,"Segoe UI",sans-serif;margin:0;color:#1d1d1f;background:#fafafa}
+header{background:#111;color:#fff;padding:1rem 2rem}header a{color:#ffd400;text-decoration:none}
+main{padding:1rem 2rem;max-width:1200px}
+nav a{margin-right:1rem}
+table{border-collapse:collapse;width:100%;background:#fff}
+th,td{text-align:left;padding:.4rem .6rem;border-bottom:1px solid #e3e3e3;vertical-align:middle}
+th{background:#f0f0f0}
+code{font-size:.85em}
+img.thumb{max-width:120px;max-height:120px;display:block}
+.badge{display:inline-block;padding:.1rem .4rem;border-radius:.3rem;background:#c62828;color:#fff;font-size:.75em}
+.muted{color:#666}
+</style>
+</head>
+<body>
+<header><a href="index.html">{{.Site}} Documentation Backup</a> · {{.Title}}</header>
+<main>
+<nav>{{range .Types}}<a href="type-{{.Name}}.html">{{.Label}} ({{len .Files}})</a>{{end}}</nav>
+{{end}}
+
+{{define "foot"}}
+<p class="muted">Last scrape: {{date .LastRun}}</p>
+</main>
+</body>
+</html>
+{{end}}
+
+{{define "files"}}
+<table>
+<tr><th></th><th>File</th><th>Size</th><th>SHA-256</th><th>First seen</th><th>Source</th></tr>
+{{range .}}<tr>
+<td>{{if .Thumbnail}}<img class="thumb" src="{{.Thumbnail}}" alt="">{{end}}</td>
+<td><a href="{{.Href}}">{{.Name}}</a>{{if .Removed}} <span class="badge">removed upstream</span>{{end}}{{if .ProductSlug}}<br><a class="muted" href="product-{{.ProductSlug}}.html">{{.Product}}</a>{{end}}</td>
+<td>{{size .Size}}</td>
+<td><code title="{{.SHA256}}">{{short .SHA256}}</code></td>
+<td>{{date .FirstSeen}}</td>
+<td>{{if .URL}}<a href="{{.URL}}">source</a>{{end}}</td>
+</tr>{{end}}
+</table>
+{{end}}
+
+{{define "index"}}{{template "head" .}}
+<h1>Products</h1>
+<p>{{.Total}} files{{if .Removed}}, {{.Removed}} removed upstream{{end}}.</p>
+<table>
+<tr><th>Product</th><th>Files</th></tr>
+{{range .Products}}<tr><td><a href="product-{{.Slug}}.html">{{.Name}}</a></td><td>{{.Count}}</td></tr>{{end}}
+</table>
+{{template "foot" .}}{{end}}
+
+{{define "product"}}{{template "head" .}}
+<h1>{{.Product.Name}}</h1>
+{{range .Product.Sections}}<h2>{{.Title}}</h2>
+{{template "files" .Files}}{{end}}
+{{template "foot" .}}{{end}}
+
+{{define "type"}}{{template "head" .}}
+<h1>{{.Type.Label}} files</h1>
+{{template "files" .Type.Files}}
+{{template "foot" .}}{{end}}
+`))
+
+// runSite renders the static HTML browser from the manifest. It returns the process exit code.
+func runSite(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("site", flag.ExitOnError)
+	outputDir := flags.String("out", defaultSiteDir, "directory to write the site to")
+	flags.Parse(args)
+
+	archive := loadManifest(manifestPath)
+	if !renderSite(archive, buildCatalog(archive), *outputDir) {
+		return 1
+	}
+	return 0
+}
+
+// renderSite writes index.html, one page per product and one page per asset type into outputDir.
+// Links to archived files are relative, so the site works when the repository root is published (e.g. GitHub Pages).
+func renderSite(archive *archiveManifest, catalog *productCatalog, outputDir string) bool {
+	// Check if the site directory exists
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		log.Printf("Failed to create %s: %v", outputDir, err)
+		return false
+	}
+	// Links from the site pages back to the archive root
+	absoluteOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		log.Printf("Failed to resolve %s: %v", outputDir, err)
+		return false
+	}
+	absoluteRoot, err := filepath.Abs(".")
+	if err != nil {
+		log.Printf("Failed to resolve the archive root: %v", err)
+		return false
+	}
+	archiveRoot, err := filepath.Rel(absoluteOutput, absoluteRoot)
+	if err != nil {
+		log.Printf("Failed to locate the archive from %s: %v", outputDir, err)
+		return false
+	}
+
+	// Build the file rows once, keyed by archive path
+	filesByPath := make(map[string]siteFile)
+	for _, product := range catalog.Products {
+		for _, files := range product.Files {
+			for _, file := range files {
+				entry := archive.Entries[file.Path]
+				row := siteFile{
+					Name:        path.Base(file.Path),
+					Href:        path.Join(filepath.ToSlash(archiveRoot), file.Path),
+					URL:         file.URL,
+					Size:        file.Size,
+					SHA256:      file.SHA256,
+					Product:     product.Name,
+					ProductSlug: product.Slug,
+				}
+				if entry != nil {
+					row.FirstSeen = entry.FirstSeen
+					row.Removed = archive.removedUpstream(entry)
+				}
+				if asset := assetTypeByExtension(path.Ext(file.Path)); asset != nil && asset.Category == "images" {
+					row.Thumbnail = thumbnailDataURI(filepath.FromSlash(file.Path))
+				} else if file.Preview != "" {
+					row.Thumbnail = thumbnailDataURI(filepath.FromSlash(file.Preview))
+				}
+				filesByPath[file.Path] = row
+			}
+		}
+	}
+
+	// Group rows into product pages and type pages
+	page := sitePage{Site: currentSite.title(), LastRun: archive.LastRun, Total: len(filesByPath)}
+	for _, row := range filesByPath {
+		if row.Removed {
+			page.Removed++
+		}
+	}
+	for _, product := range catalog.Products {
+		siteEntry := &siteProduct{Name: product.Name, Slug: product.Slug}
+		for _, category := range catalogCategories {
+			var rows []siteFile
+			for _, file := range product.Files[category.Key] {
+				row := filesByPath[file.Path]
+				row.ProductSlug = "" // Already on the product page
+				rows = append(rows, row)
+			}
+			if len(rows) > 0 {
+				siteEntry.Sections = append(siteEntry.Sections, siteSection{Title: category.Title, Files: rows})
+				siteEntry.Count += len(rows)
+			}
+		}
+		page.Products = append(page.Products, siteEntry)
+	}
+	for _, asset := range assetTypes {
+		typeEntry := &siteType{Name: asset.Name, Label: asset.Label}
+		for _, filePath := range slices.Sorted(maps.Keys(filesByPath)) {
+			// Classify by extension like buildCatalog, so files extracted under ZIPs/<name>/ list under their own type
+			if fileAsset := assetTypeByExtension(filepath.Ext(filePath)); fileAsset != nil && fileAsset.Name == asset.Name {
+				typeEntry.Files = append(typeEntry.Files, filesByPath[filePath])
+			}
+		}
+		page.Types = append(page.Types, typeEntry)
+	}
+
+	// Render every page
+	rendered := 0
+	page.Title = "Products"
+	if !renderSitePage(filepath.Join(outputDir, "index.html"), "index", page) {
+		return false
+	}
+	rendered++
+	productPages := make(map[string]bool)
+	for _, product := range page.Products {
+		productPage := page
+		productPage.Title = product.Name
+		productPage.Product = product
+		pageName := "product-" + product.Slug + ".html"
+		productPages[pageName] = true
+		if !renderSitePage(filepath.Join(outputDir, pageName), "product", productPage) {
+			return false
+		}
+		rendered++
+	}
+	for _, typeEntry := range page.Types {
+		typePage := page
+		typePage.Title = typeEntry.Label + " files"
+		typePage.Type = typeEntry
+		if !renderSitePage(filepath.Join(outputDir, "type-"+typeEntry.Name+".html"), "type", typePage) {
+			return false
+		}
+		rendered++
+	}
+
+	// Remove the pages of products that are no longer in the catalog
+	existingPages, err := filepath.Glob(filepath.Join(outputDir, "product-*.html"))
+	if err != nil {
+		log.Printf("Failed to list the product pages in %s: %v", outputDir, err)
+		return false
+	}
+	for _, existingPage := range existingPages {
+		if !productPages[filepath.Base(existingPage)] {
+			if err := os.Remove(existingPage); err != nil {
+				log.Printf("Failed to remove stale product page %s: %v", existingPage, err)
+			}
+		}
+	}
+	log.Printf("Rendered %d pages for %d files into %s", rendered, page.Total, outputDir)
+	return true
+}
+
+// renderSitePage executes one template into a file.
+func renderSitePage(filePath, templateName string, page sitePage) bool {
+	var buffer bytes.Buffer
+	if err := siteTemplates.ExecuteTemplate(&buffer, templateName, page); err != nil {
+		log.Printf("Failed to render %s: %v", filePath, err)
+		return false
+	}
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0o644); err != nil {
+		log.Printf("Failed to write %s: %v", filePath, err)
+		return false
+	}
+	return true
+}
+
+// thumbnailDataURI decodes an image and returns a small JPEG thumbnail as a data: URI, or "" if it cannot be decoded.
+func thumbnailDataURI(imagePath string) template.URL {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	// Skip images whose declared size would take gigabytes to decode
+	if config, _, err := image.DecodeConfig(file); err != nil || int64(config.Width)*int64(config.Height) > maxDecodedImagePixels {
+		log.Printf("Skipping the thumbnail of %s: unreadable or too large to decode", imagePath)
+		return ""
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	source, _, err := image.Decode(file)
+	if err != nil {
+		log.Printf("Failed to decode %s for a thumbnail: %v", imagePath, err)
+		return ""
+	}
+
+	// Scale the longest edge down to thumbnailSize, keeping the aspect ratio
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return ""
+	}
+	scale := min(1.0, float64(thumbnailSize)/float64(max(width, height)))
+	thumbWidth, thumbHeight := max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+
+	// Composite onto white so transparent PNGs stay readable as JPEG
+	thumbnail := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		for x := 0; x < thumbWidth; x++ {
+			// Nearest-neighbour sampling is enough at thumbnail size
+			red, green, blue, alpha := source.At(bounds.Min.X+x*width/thumbWidth, bounds.Min.Y+y*height/thumbHeight).RGBA()
+			background := 0xffff - alpha // Premultiplied colours only need the white remainder added
+			thumbnail.SetRGBA(x, y, color.RGBA{
+				R: uint8((red + background) >> 8),
+				G: uint8((green + background) >> 8),
+				B: uint8((blue + background) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: 75}); err != nil {
+		return ""
+	}
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(encoded.Bytes()))
+}
@@ -0,0 +1,65 @@
+package main // Define the main package
+
+import (
+	"os"            // Provides file checks
+	"path/filepath" // Provides filepath manipulation functions
+	"testing"       // Provides the test runner
+)
+
+// TestRenderSiteRemovesStalePages checks that re-rendering drops the pages of products that left the
+// catalog and keeps the current ones and unrelated files.
+func TestRenderSiteRemovesStalePages(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputDir := "site"
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"product-old-camera.html", "custom.html"} {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := &archiveManifest{Entries: make(map[string]*manifestEntry)}
+	catalog := &productCatalog{Products: []*catalogProduct{{
+		Name:  "Ratel",
+		Slug:  "ratel",
+		Files: map[string][]catalogFile{"manuals": {{Path: "PDFs/ratel_manual.pdf"}}},
+	}}}
+	if !renderSite(archive, catalog, outputDir) {
+		t.Fatal("renderSite failed")
+	}
+	for name, expected := range map[string]bool{
+		"index.html":              true,
+		"product-ratel.html":      true,
+		"type-pdf.html":           true,
+		"custom.html":             true,
+		"product-old-camera.html": false,
+	} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); (err == nil) != expected {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, expected)
+		}
+	}
+}
+
+// TestThumbnailDataURI checks that thumbnails are made for normal images and skipped for images that
+// claim more pixels than can be decoded safely.
+func TestThumbnailDataURI(t *testing.T) {
+	small := testPNG(t, 64)
+	for name, test := range map[string]struct {
+		data     []byte
+		expected bool
+	}{
+		"small image":          {small, true},
+		"declared 30000×30000": {withPNGSize(small, 30000, 30000), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			imagePath := filepath.Join(t.TempDir(), "image.png")
+			if err := os.WriteFile(imagePath, test.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if thumbnail := thumbnailDataURI(imagePath); (thumbnail != "") != test.expected {
+				t.Errorf("thumbnail made = %v, want %v", thumbnail != "", test.expected)
+			}
+		})
+	}
+}
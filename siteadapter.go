@@ -0,0 +1,183 @@
+package main // Define the main package
+
+import (
+	"fmt"           // Provides formatted output for the site list
+	"log"           // Provides logging functions
+	"maps"          // Provides map iteration helpers
+	"net/url"       // Provides URL parsing and encoding
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string manipulation functions
+)
+
+// siteAdapter describes one vendor website the archiver can preserve: where the crawl starts, which hosts
+// it may fetch from, how asset links are found on a page, how downloaded files are named and how they are
+// grouped into products. Everything else (validation, manifest, catalog, previews) is shared.
+type siteAdapter interface {
+	name() string                                           // Short name used with -site and as the output folder
+	title() string                                          // Display name, e.g. "Caddx FPV"
+	seeds() []string                                        // Pages the crawl starts from
+	locales() []string                                      // Locales whose versions of the seeds can also be crawled
+	localeURL(pageURL, locale string) string                // The page's URL in another locale
+	allowedHost(host string) bool                           // Whether pages (and files with -restrict-hosts) may be fetched from the host
+	extractLinks(pageData, pageURL string) []discoveredLink // Asset links found on one page
+	nextPage(pageData, pageURL string) string               // URL of the page after this one in a listing, or ""
+	pagePattern() string                                    // Numbered page URL tried when a listing has no next link
+	fileName(assetURL string) string                        // File name of a downloaded asset in its type folder
+	product(entry *manifestEntry) string                    // Product an archived file belongs to
+}
+
+// defaultSiteName is the adapter used without -site; its archive is the repository root.
+const defaultSiteName = "caddx"
+
+// sitesDir holds the archive of every other site, one folder per adapter name.
+const sitesDir = "sites/"
+
+// siteAdapters lists the built-in adapters by name.
+var siteAdapters = map[string]siteAdapter{
+	caddxSite.Name:     caddxSite,
+	walksnailSite.Name: walksnailSite,
+}
+
+// currentSite is the adapter selected for this run.
+var currentSite siteAdapter = caddxSite
+
+// shopifySite is an adapter for a Shopify storefront: links come from the page markup and its script data,
+// CDN thumbnails are replaced by the original uploads and products are recognised by token rules.
+type shopifySite struct {
+	Name     string        // Short name used with -site
+	Title    string        // Display name of the vendor
+	Seeds    []string      // Pages the crawl starts from
+	Locales  []string      // Locale prefixes the storefront serves, e.g. "zh-cn"
+	Hosts    []string      // Domains of the storefront; subdomains are allowed too
+	Pages    string        // Numbered page pattern such as "?page={page}"; "" follows only next links
+	Products []productRule // Product rules, most specific first
+}
+
+func (site *shopifySite) name() string      { return site.Name }
+func (site *shopifySite) title() string     { return site.Title }
+func (site *shopifySite) seeds() []string   { return site.Seeds }
+func (site *shopifySite) locales() []string { return site.Locales }
+
+// localeURL puts the locale in front of the path, which is how Shopify serves translated pages
+// (e.g. /pages/download-center becomes /zh-cn/pages/download-center).
+func (site *shopifySite) localeURL(pageURL, locale string) string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	parsedURL.Path = "/" + strings.Trim(strings.TrimSpace(locale), "/") + parsedURL.Path
+	parsedURL.RawPath = ""
+	return parsedURL.String()
+}
+
+// allowedHost accepts the storefront's domains, their subdomains and the Shopify CDN that serves their files.
+func (site *shopifySite) allowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "cdn.shopify.com" {
+		return true
+	}
+	for _, domain := range site.Hosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractLinks collects the <a> and <img> links and the URLs inside <script> blocks, and asks for the
+// original images instead of the resized variants the page shows.
+func (site *shopifySite) extractLinks(pageData, pageURL string) []discoveredLink {
+	links := extractAssetLinks(pageData, pageURL)
+	links = append(links, extractScriptURLs(pageData, pageURL)...)
+	return normalizeShopifyLinks(links)
+}
+
+// nextPage follows the rel="next" links Shopify themes emit and the common pagination markup.
+func (site *shopifySite) nextPage(pageData, pageURL string) string {
+	return findNextPageURL(pageData, pageURL)
+}
+
+func (site *shopifySite) pagePattern() string { return site.Pages }
+
+func (site *shopifySite) fileName(assetURL string) string { return urlToFilename(assetURL) }
+
+func (site *shopifySite) product(entry *manifestEntry) string {
+	return productFromRules(entry, site.Products)
+}
+
+// cutSiteOption removes a leading "-site name" or "-site=name" from the arguments and returns the
+// adapter name, which defaults to defaultSiteName.
+func cutSiteOption(args []string) (string, []string) {
+	if len(args) == 0 {
+		return defaultSiteName, args
+	}
+	option := strings.TrimPrefix(args[0], "-")
+	if value, found := strings.CutPrefix(option, "-site="); found {
+		return value, args[1:]
+	}
+	if value, found := strings.CutPrefix(option, "site="); found {
+		return value, args[1:]
+	}
+	if (option == "site" || option == "-site") && len(args) > 1 {
+		return args[1], args[2:]
+	}
+	return defaultSiteName, args
+}
+
+// selectSite makes the named adapter current and changes into its output root, creating it if needed,
+// so every command reads and writes that site's manifest, type folders and catalog.
+func selectSite(name string) bool {
+	adapter, found := siteAdapters[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Unknown site %q (expected one of %s)\n", name, strings.Join(slices.Sorted(maps.Keys(siteAdapters)), ", "))
+		return false
+	}
+	currentSite = adapter
+	root := siteOutputRoot(adapter)
+	if root == "." {
+		return true
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		log.Printf("Failed to create %s: %v", root, err)
+		return false
+	}
+	if err := os.Chdir(root); err != nil {
+		log.Printf("Failed to enter %s: %v", root, err)
+		return false
+	}
+	return true
+}
+
+// siteOutputRoot returns the directory holding a site's archive: the repository root for the default
+// site and sites/<name>/ for the others, so archives of different vendors never mix.
+func siteOutputRoot(adapter siteAdapter) string {
+	if adapter.name() == defaultSiteName {
+		return "."
+	}
+	return filepath.Join(sitesDir, adapter.name())
+}
+
+// isAllowedURL reports whether the selected site may fetch the URL.
+func isAllowedURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	return err == nil && currentSite.allowedHost(parsedURL.Hostname())
+}
+
+// filterAllowedLinks drops the links to hosts the selected site does not allow, logging each skipped URL once.
+func filterAllowedLinks(links []discoveredLink) []discoveredLink {
+	skipped := make(map[string]bool)
+	allowed := links[:0]
+	for _, link := range links {
+		if isAllowedURL(link.URL) {
+			allowed = append(allowed, link)
+			continue
+		}
+		if !skipped[link.URL] {
+			skipped[link.URL] = true
+			log.Printf("Skipping %s: host is not allowed for site %s", link.URL, currentSite.name())
+		}
+	}
+	return allowed
+}
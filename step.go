@@ -0,0 +1,409 @@
+package main // Define the main package
+
+import (
+	"bytes"         // Provides bytes support
+	"errors"        // Provides error values
+	"fmt"           // Provides formatted errors
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"regexp"        // Provides the schema identifier pattern
+	"strconv"       // Provides hex parsing of encoded characters
+	"strings"       // Provides string manipulation functions
+	"unicode/utf16" // Decodes \X2\ encoded strings
+)
+
+// stepMetadata is the summary of a STEP (ISO 10303-21) file stored in the manifest.
+type stepMetadata struct {
+	Description         []string       `json:"description,omitempty"`          // FILE_DESCRIPTION lines
+	ImplementationLevel string         `json:"implementation_level,omitempty"` // FILE_DESCRIPTION conformance level, e.g. "2;1"
+	Name                string         `json:"name,omitempty"`                 // FILE_NAME name, often the original file or assembly name
+	Timestamp           string         `json:"timestamp,omitempty"`            // FILE_NAME time stamp as written by the exporter
+	Authors             []string       `json:"authors,omitempty"`              // FILE_NAME author lines
+	Organizations       []string       `json:"organizations,omitempty"`        // FILE_NAME organization lines
+	Preprocessor        string         `json:"preprocessor,omitempty"`         // FILE_NAME preprocessor version (the STEP translator)
+	OriginatingSystem   string         `json:"originating_system,omitempty"`   // FILE_NAME originating system (the CAD program)
+	Authorization       string         `json:"authorization,omitempty"`        // FILE_NAME authorisation
+	Schemas             []string       `json:"schemas,omitempty"`              // FILE_SCHEMA names
+	Protocol            string         `json:"protocol,omitempty"`             // Application protocol of the first schema, e.g. "AP214"
+	Entities            int            `json:"entities"`                       // Entity instances in the DATA sections
+	EntityTypes         map[string]int `json:"entity_types,omitempty"`         // Instances per entity type; complex instances count once per part
+	Products            []string       `json:"products,omitempty"`             // Names of the PRODUCT entities (parts and assemblies)
+}
+
+// stepSchemaPart matches the part number in a schema's object identifier, e.g. "{ 1 0 10303 214 1 1 1 1 }".
+var stepSchemaPart = regexp.MustCompile(`\b10303\s+(\d+)\b`)
+
+// stepProtocolNames maps schema names without an object identifier to their application protocol.
+var stepProtocolNames = map[string]string{
+	"CONFIG_CONTROL_DESIGN": "AP203",
+	"AUTOMOTIVE_DESIGN":     "AP214",
+	"AP203_CONFIGURATION_CONTROLLED_3D_DESIGN_OF_MECHANICAL_PARTS_AND_ASSEMBLIES_MIM_LF": "AP203",
+	"AP214_AUTOMOTIVE_DESIGN":                         "AP214",
+	"AP242_MANAGED_MODEL_BASED_3D_ENGINEERING_MIM_LF": "AP242",
+}
+
+// stepBodyTypes are the entity types that carry the model geometry, listed by inspect.
+var stepBodyTypes = []string{
+	"MANIFOLD_SOLID_BREP",
+	"BREP_WITH_VOIDS",
+	"FACETED_BREP",
+	"SHELL_BASED_SURFACE_MODEL",
+	"ADVANCED_FACE",
+	"PRODUCT_DEFINITION",
+	"NEXT_ASSEMBLY_USAGE_OCCURRENCE",
+}
+
+// parseSTEP reads the HEADER section and counts the entities of the DATA sections of an exchange file.
+func parseSTEP(data []byte) (*stepMetadata, error) {
+	metadata := &stepMetadata{EntityTypes: make(map[string]int)}
+	section := ""
+	sawHeader := false
+	seenProducts := make(map[string]bool)
+	err := forEachSTEPStatement(data, func(statement []byte) error {
+		keyword, parameters := splitSTEPStatement(statement)
+		switch keyword {
+		case "HEADER", "DATA":
+			section = keyword
+			sawHeader = sawHeader || keyword == "HEADER"
+			return nil
+		case "ENDSEC":
+			section = ""
+			return nil
+		}
+
+		switch section {
+		case "HEADER":
+			return metadata.readHeaderEntity(keyword, parameters)
+		case "DATA":
+			// Instances look like "#12=TYPE(...)" or, for complex instances, "#12=(TYPE_A(...)TYPE_B(...))"
+			_, instance, found := bytes.Cut(statement, []byte("="))
+			if !found {
+				return nil
+			}
+			metadata.Entities++
+			instance = bytes.TrimSpace(instance)
+			if bytes.HasPrefix(instance, []byte("(")) {
+				parser := &stepParser{text: instance, position: 1}
+				for {
+					name := parser.keyword()
+					if name == "" {
+						break
+					}
+					metadata.EntityTypes[name]++
+					if _, err := parser.parameters(); err != nil {
+						return fmt.Errorf("complex entity %s: %v", name, err)
+					}
+				}
+				return nil
+			}
+			name, arguments := splitSTEPStatement(instance)
+			metadata.EntityTypes[name]++
+			if name == "PRODUCT" {
+				// PRODUCT(id, name, description, frame_of_reference); exporters often leave the name empty
+				values, err := (&stepParser{text: arguments}).parameters()
+				if err != nil {
+					return fmt.Errorf("PRODUCT: %v", err)
+				}
+				product := stepString(values, 1)
+				if product == "" {
+					product = stepString(values, 0)
+				}
+				if product != "" && !seenProducts[product] {
+					seenProducts[product] = true
+					metadata.Products = append(metadata.Products, product)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !sawHeader {
+		return nil, errors.New("no HEADER section")
+	}
+	return metadata, nil
+}
+
+// readHeaderEntity fills in the metadata from one of the three mandatory header entities.
+func (metadata *stepMetadata) readHeaderEntity(keyword string, arguments []byte) error {
+	if keyword != "FILE_DESCRIPTION" && keyword != "FILE_NAME" && keyword != "FILE_SCHEMA" {
+		return nil // Optional header entities are not summarised
+	}
+	values, err := (&stepParser{text: arguments}).parameters()
+	if err != nil {
+		return fmt.Errorf("%s: %v", keyword, err)
+	}
+	switch keyword {
+	case "FILE_DESCRIPTION":
+		metadata.Description = stepStrings(values, 0)
+		metadata.ImplementationLevel = stepString(values, 1)
+	case "FILE_NAME":
+		metadata.Name = stepString(values, 0)
+		metadata.Timestamp = stepString(values, 1)
+		metadata.Authors = stepStrings(values, 2)
+		metadata.Organizations = stepStrings(values, 3)
+		metadata.Preprocessor = stepString(values, 4)
+		metadata.OriginatingSystem = stepString(values, 5)
+		metadata.Authorization = stepString(values, 6)
+	case "FILE_SCHEMA":
+		metadata.Schemas = stepStrings(values, 0)
+		if len(metadata.Schemas) > 0 {
+			metadata.Protocol = stepProtocol(metadata.Schemas[0])
+		}
+	}
+	return nil
+}
+
+// stepProtocol names the application protocol of a schema, from its object identifier or its name.
+func stepProtocol(schema string) string {
+	if match := stepSchemaPart.FindStringSubmatch(schema); match != nil {
+		return "AP" + match[1]
+	}
+	name, _, _ := strings.Cut(strings.ToUpper(strings.TrimSpace(schema)), " ")
+	name, _, _ = strings.Cut(name, "{")
+	return stepProtocolNames[name]
+}
+
+// forEachSTEPStatement calls visit with every ";"-terminated statement, skipping comments
+// and keeping semicolons inside strings.
+func forEachSTEPStatement(data []byte, visit func(statement []byte) error) error {
+	var statement []byte
+	for position := 0; position < len(data); position++ {
+		character := data[position]
+		switch {
+		case character == '\'':
+			// Strings end at a single quote; doubled quotes are escaped quotes
+			end := position + 1
+			for end < len(data) {
+				if data[end] == '\'' {
+					if end+1 < len(data) && data[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(data) {
+				return errors.New("unterminated string")
+			}
+			statement = append(statement, data[position:end+1]...)
+			position = end
+		case character == '/' && position+1 < len(data) && data[position+1] == '*':
+			end := bytes.Index(data[position+2:], []byte("*/"))
+			if end < 0 {
+				return errors.New("unterminated comment")
+			}
+			position += end + 3
+		case character == ';':
+			if err := visit(bytes.TrimSpace(statement)); err != nil {
+				return err
+			}
+			statement = statement[:0]
+		case character == '\r' || character == '\n':
+			// Line breaks carry no meaning outside strings
+		default:
+			statement = append(statement, character)
+		}
+	}
+	return nil
+}
+
+// splitSTEPStatement splits "KEYWORD(arguments)" into the upper-case keyword and "(arguments)".
+func splitSTEPStatement(statement []byte) (string, []byte) {
+	open := bytes.IndexByte(statement, '(')
+	if open < 0 {
+		return strings.ToUpper(string(bytes.TrimSpace(statement))), nil
+	}
+	return strings.ToUpper(string(bytes.TrimSpace(statement[:open]))), statement[open:]
+}
+
+// stepParser reads the parameter lists of exchange file entities.
+type stepParser struct {
+	text     []byte
+	position int
+}
+
+// skipSpace moves past whitespace.
+func (parser *stepParser) skipSpace() {
+	for parser.position < len(parser.text) && (parser.text[parser.position] == ' ' || parser.text[parser.position] == '\t') {
+		parser.position++
+	}
+}
+
+// keyword reads an entity or type name, or returns "" when there is none.
+func (parser *stepParser) keyword() string {
+	parser.skipSpace()
+	start := parser.position
+	for parser.position < len(parser.text) {
+		character := parser.text[parser.position]
+		if character != '_' && character != '-' && (character < 'A' || character > 'Z') && (character < 'a' || character > 'z') && (character < '0' || character > '9') {
+			break
+		}
+		parser.position++
+	}
+	return strings.ToUpper(string(parser.text[start:parser.position]))
+}
+
+// parameters reads a parenthesised, comma-separated list of values.
+func (parser *stepParser) parameters() ([]any, error) {
+	parser.skipSpace()
+	if parser.position >= len(parser.text) || parser.text[parser.position] != '(' {
+		return nil, errors.New("expected a parameter list")
+	}
+	parser.position++
+	var values []any
+	for {
+		parser.skipSpace()
+		if parser.position >= len(parser.text) {
+			return nil, errors.New("unterminated parameter list")
+		}
+		if parser.text[parser.position] == ')' {
+			parser.position++
+			return values, nil
+		}
+		value, err := parser.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		parser.skipSpace()
+		if parser.position < len(parser.text) && parser.text[parser.position] == ',' {
+			parser.position++
+		}
+	}
+}
+
+// value reads one value: a string, a list, a typed value (returned as its list), or any other
+// token (number, enumeration, reference, $ or *) as its text.
+func (parser *stepParser) value() (any, error) {
+	parser.skipSpace()
+	switch character := parser.text[parser.position]; {
+	case character == '(':
+		return parser.parameters()
+	case character == '\'':
+		start := parser.position + 1
+		end := start
+		for end < len(parser.text) {
+			if parser.text[end] == '\'' {
+				if end+1 < len(parser.text) && parser.text[end+1] == '\'' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		if end >= len(parser.text) {
+			return nil, errors.New("unterminated string")
+		}
+		parser.position = end + 1
+		return decodeSTEPString(parser.text[start:end]), nil
+	case character >= 'A' && character <= 'Z' || character >= 'a' && character <= 'z':
+		// Typed value such as LENGTH_MEASURE(2.0)
+		parser.keyword()
+		return parser.parameters()
+	default:
+		start := parser.position
+		for parser.position < len(parser.text) && parser.text[parser.position] != ',' && parser.text[parser.position] != ')' {
+			parser.position++
+		}
+		return strings.TrimSpace(string(parser.text[start:parser.position])), nil
+	}
+}
+
+// decodeSTEPString undoes the exchange file string encoding: doubled quotes and backslashes,
+// \S\ (ISO 8859-1 upper half), \X\hh (one ISO 8859-1 byte), \X2\ (UTF-16) and \X4\ (UCS-4) runs.
+// Code page switches (\P?\) are ignored.
+func decodeSTEPString(raw []byte) string {
+	var decoded strings.Builder
+	text := string(raw)
+	for index := 0; index < len(text); index++ {
+		character := text[index]
+		switch {
+		case character == '\'' && strings.HasPrefix(text[index:], "''"):
+			decoded.WriteByte('\'')
+			index++
+		case character != '\\':
+			decoded.WriteByte(character)
+		case strings.HasPrefix(text[index:], `\\`):
+			decoded.WriteByte('\\')
+			index++
+		case strings.HasPrefix(text[index:], `\S\`) && index+3 < len(text):
+			decoded.WriteRune(rune(text[index+3]) + 0x80)
+			index += 3
+		case strings.HasPrefix(text[index:], `\X\`) && index+5 <= len(text):
+			if value, err := strconv.ParseUint(text[index+3:index+5], 16, 8); err == nil {
+				decoded.WriteRune(rune(value))
+				index += 4
+			} else {
+				decoded.WriteByte(character)
+			}
+		case strings.HasPrefix(text[index:], `\X2\`) || strings.HasPrefix(text[index:], `\X4\`):
+			width := 4
+			if text[index+2] == '4' {
+				width = 8
+			}
+			end := strings.Index(text[index+4:], `\X0\`)
+			if end < 0 {
+				decoded.WriteByte(character)
+				continue
+			}
+			hex := text[index+4 : index+4+end]
+			var units []uint16
+			for start := 0; start+width <= len(hex); start += width {
+				value, err := strconv.ParseUint(hex[start:start+width], 16, 32)
+				if err != nil {
+					break
+				}
+				if width == 8 {
+					decoded.WriteRune(rune(value))
+				} else {
+					units = append(units, uint16(value))
+				}
+			}
+			decoded.WriteString(string(utf16.Decode(units)))
+			index += 4 + end + 3
+		case len(text) > index+3 && text[index+1] == 'P' && text[index+3] == '\\':
+			index += 3 // Code page switch
+		default:
+			decoded.WriteByte(character)
+		}
+	}
+	return decoded.String()
+}
+
+// stepString returns the string parameter at index, or "" when it is missing, unset ($) or not a string.
+func stepString(values []any, index int) string {
+	if index >= len(values) {
+		return ""
+	}
+	if text, ok := values[index].(string); ok && text != "$" && text != "*" {
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
+
+// stepStrings returns the non-empty strings of the list parameter at index.
+func stepStrings(values []any, index int) []string {
+	if index >= len(values) {
+		return nil
+	}
+	list, _ := values[index].([]any)
+	var texts []string
+	for item := range list {
+		if text := stepString(list, item); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// parseSTEPFile parses the STEP file at filePath.
+func parseSTEPFile(filePath string) (*stepMetadata, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseSTEP(data)
+}
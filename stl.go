@@ -0,0 +1,312 @@
+package main // Define the main package
+
+import (
+	"bytes"           // Provides bytes support
+	"encoding/binary" // Provides little-endian decoding of binary STL
+	"errors"          // Provides error values
+	"fmt"             // Provides formatted errors
+	"math"            // Provides float helpers
+	"os"              // Provides functions to interact with the OS (files, etc.)
+	"strconv"         // Provides float parsing for ASCII STL
+)
+
+// stlTriangle is one facet: the stored normal and three vertices.
+type stlTriangle struct {
+	Normal   [3]float32
+	Vertices [3][3]float32
+}
+
+// stlMesh is a parsed STL file.
+type stlMesh struct {
+	Format    string // "binary" or "ascii"
+	Triangles []stlTriangle
+}
+
+// stlAnalysis is the geometry summary stored in the manifest. STL files have no units; millimetres are assumed.
+type stlAnalysis struct {
+	Format           string     `json:"format"`             // "binary" or "ascii"
+	Triangles        int        `json:"triangles"`          // Number of facets
+	Min              [3]float64 `json:"min"`                // Bounding box corner (mm)
+	Max              [3]float64 `json:"max"`                // Opposite bounding box corner (mm)
+	Size             [3]float64 `json:"size"`               // Bounding box dimensions (mm)
+	SurfaceArea      float64    `json:"surface_area"`       // mm²
+	Volume           float64    `json:"volume"`             // Signed volume in mm³; negative when the mesh is inside out
+	NonManifoldEdges int        `json:"non_manifold_edges"` // Edges not shared by exactly two facets
+	FlippedNormals   int        `json:"flipped_normals"`    // Facets wound against their neighbours
+	NormalMismatches int        `json:"normal_mismatches"`  // Stored normals pointing against the vertex winding
+	Watertight       bool       `json:"watertight"`         // No non-manifold edges
+}
+
+// parseSTL parses a binary or ASCII STL. Binary is detected by the triangle count matching the file size,
+// because many binary files also start their header with "solid".
+func parseSTL(data []byte) (*stlMesh, error) {
+	if len(data) >= 84 {
+		count := uint64(binary.LittleEndian.Uint32(data[80:]))
+		if 84+50*count == uint64(len(data)) {
+			return parseBinarySTL(data, int(count))
+		}
+	}
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(bytes.ToLower(trimmed[:min(len(trimmed), 5)]), []byte("solid")) {
+		return parseASCIISTL(trimmed)
+	}
+	return nil, errors.New("not a binary STL (size does not match the triangle count) or an ASCII STL")
+}
+
+// parseBinarySTL reads count 50-byte facet records after the 84-byte header.
+func parseBinarySTL(data []byte, count int) (*stlMesh, error) {
+	mesh := &stlMesh{Format: "binary", Triangles: make([]stlTriangle, count)}
+	readVector := func(offset int) [3]float32 {
+		return [3]float32{
+			math.Float32frombits(binary.LittleEndian.Uint32(data[offset:])),
+			math.Float32frombits(binary.LittleEndian.Uint32(data[offset+4:])),
+			math.Float32frombits(binary.LittleEndian.Uint32(data[offset+8:])),
+		}
+	}
+	for index := range mesh.Triangles {
+		offset := 84 + 50*index
+		mesh.Triangles[index].Normal = readVector(offset)
+		for vertex := 0; vertex < 3; vertex++ {
+			mesh.Triangles[index].Vertices[vertex] = readVector(offset + 12 + 12*vertex)
+			if !isFiniteVector(mesh.Triangles[index].Vertices[vertex]) {
+				return nil, fmt.Errorf("facet %d vertex %d is not a finite coordinate", index+1, vertex+1)
+			}
+		}
+	}
+	return mesh, nil
+}
+
+// isFiniteVector reports whether no coordinate is NaN or infinite. Such vertices would make the bounding
+// box and volume unencodable in the manifest.
+func isFiniteVector(vector [3]float32) bool {
+	for _, value := range vector {
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseASCIISTL reads "facet normal ... outer loop vertex ... endloop endfacet" blocks.
+func parseASCIISTL(data []byte) (*stlMesh, error) {
+	mesh := &stlMesh{Format: "ascii"}
+	fields := bytes.Fields(data)
+	readVector := func(position int) ([3]float32, error) {
+		var vector [3]float32
+		if position+3 > len(fields) {
+			return vector, errors.New("unexpected end of file")
+		}
+		for axis := 0; axis < 3; axis++ {
+			value, err := strconv.ParseFloat(string(fields[position+axis]), 32)
+			if err != nil {
+				return vector, fmt.Errorf("invalid number %q", fields[position+axis])
+			}
+			vector[axis] = float32(value)
+		}
+		return vector, nil
+	}
+
+	var current stlTriangle
+	vertexCount := 0
+	for position := 0; position < len(fields); position++ {
+		switch string(bytes.ToLower(fields[position])) {
+		case "facet":
+			current, vertexCount = stlTriangle{}, 0
+			if position+1 < len(fields) && bytes.EqualFold(fields[position+1], []byte("normal")) {
+				normal, err := readVector(position + 2)
+				if err != nil {
+					return nil, fmt.Errorf("facet %d normal: %v", len(mesh.Triangles)+1, err)
+				}
+				current.Normal = normal
+				position += 4
+			}
+		case "vertex":
+			vertex, err := readVector(position + 1)
+			if err != nil {
+				return nil, fmt.Errorf("facet %d vertex: %v", len(mesh.Triangles)+1, err)
+			}
+			if !isFiniteVector(vertex) {
+				return nil, fmt.Errorf("facet %d vertex %d is not a finite coordinate", len(mesh.Triangles)+1, vertexCount+1)
+			}
+			if vertexCount == 3 {
+				return nil, fmt.Errorf("facet %d has more than three vertices", len(mesh.Triangles)+1)
+			}
+			current.Vertices[vertexCount] = vertex
+			vertexCount++
+			position += 3
+		case "endfacet":
+			if vertexCount != 3 {
+				return nil, fmt.Errorf("facet %d has %d vertices", len(mesh.Triangles)+1, vertexCount)
+			}
+			mesh.Triangles = append(mesh.Triangles, current)
+		}
+	}
+	if len(mesh.Triangles) == 0 {
+		return nil, errors.New("ASCII STL has no facets")
+	}
+	return mesh, nil
+}
+
+// stlEdge is an undirected edge between two welded vertex indices (smaller index first).
+type stlEdge [2]int
+
+// stlEdgeUse is one facet using an edge, with the direction it walks it in.
+type stlEdgeUse struct {
+	Triangle int
+	Forward  bool // Walks from the smaller to the larger vertex index
+}
+
+// analyze computes the bounding box, area, signed volume and the manifold and orientation checks.
+func (mesh *stlMesh) analyze() *stlAnalysis {
+	analysis := &stlAnalysis{Format: mesh.Format, Triangles: len(mesh.Triangles)}
+	if len(mesh.Triangles) == 0 {
+		return analysis
+	}
+	for axis := 0; axis < 3; axis++ {
+		analysis.Min[axis] = math.Inf(1)
+		analysis.Max[axis] = math.Inf(-1)
+	}
+
+	// Vertices with identical coordinates are the same vertex
+	vertexIndex := make(map[[3]float32]int)
+	edges := make(map[stlEdge][]stlEdgeUse)
+	for triangleIndex, triangle := range mesh.Triangles {
+		var corners [3][3]float64
+		var indices [3]int
+		for vertex, position := range triangle.Vertices {
+			for axis := 0; axis < 3; axis++ {
+				corners[vertex][axis] = float64(position[axis])
+				analysis.Min[axis] = math.Min(analysis.Min[axis], corners[vertex][axis])
+				analysis.Max[axis] = math.Max(analysis.Max[axis], corners[vertex][axis])
+			}
+			index, found := vertexIndex[position]
+			if !found {
+				index = len(vertexIndex)
+				vertexIndex[position] = index
+			}
+			indices[vertex] = index
+		}
+
+		// Area from the cross product; signed volume from the tetrahedron to the origin
+		edgeA := subtract3(corners[1], corners[0])
+		edgeB := subtract3(corners[2], corners[0])
+		normal := cross3(edgeA, edgeB)
+		analysis.SurfaceArea += length3(normal) / 2
+		analysis.Volume += dot3(corners[0], cross3(corners[1], corners[2])) / 6
+
+		// A stored normal pointing against the winding is a mismatch; zero normals are common and ignored
+		stored := [3]float64{float64(triangle.Normal[0]), float64(triangle.Normal[1]), float64(triangle.Normal[2])}
+		if length3(stored) > 0 && dot3(stored, normal) < 0 {
+			analysis.NormalMismatches++
+		}
+
+		for corner := 0; corner < 3; corner++ {
+			from, to := indices[corner], indices[(corner+1)%3]
+			if from == to {
+				continue // Degenerate edge
+			}
+			edge := stlEdge{min(from, to), max(from, to)}
+			edges[edge] = append(edges[edge], stlEdgeUse{Triangle: triangleIndex, Forward: from < to})
+		}
+	}
+	for axis := 0; axis < 3; axis++ {
+		analysis.Size[axis] = analysis.Max[axis] - analysis.Min[axis]
+	}
+
+	// Manifold edges are shared by exactly two facets
+	neighbours := make([][]stlEdgeUse, len(mesh.Triangles))
+	for _, uses := range edges {
+		if len(uses) != 2 {
+			analysis.NonManifoldEdges++
+			continue
+		}
+		// Consistently wound neighbours walk a shared edge in opposite directions
+		sameDirection := uses[0].Forward == uses[1].Forward
+		neighbours[uses[0].Triangle] = append(neighbours[uses[0].Triangle], stlEdgeUse{Triangle: uses[1].Triangle, Forward: sameDirection})
+		neighbours[uses[1].Triangle] = append(neighbours[uses[1].Triangle], stlEdgeUse{Triangle: uses[0].Triangle, Forward: sameDirection})
+	}
+	analysis.Watertight = analysis.NonManifoldEdges == 0
+	analysis.FlippedNormals = countFlippedTriangles(neighbours)
+
+	// Float32 coordinates carry noise far below print resolution
+	for axis := 0; axis < 3; axis++ {
+		analysis.Min[axis] = roundMillimetres(analysis.Min[axis])
+		analysis.Max[axis] = roundMillimetres(analysis.Max[axis])
+		analysis.Size[axis] = roundMillimetres(analysis.Size[axis])
+	}
+	analysis.SurfaceArea = roundMillimetres(analysis.SurfaceArea)
+	analysis.Volume = roundMillimetres(analysis.Volume)
+	return analysis
+}
+
+// roundMillimetres rounds to a ten-thousandth, well below any printer's resolution.
+func roundMillimetres(value float64) float64 {
+	return math.Round(value*1e4) / 1e4
+}
+
+// countFlippedTriangles propagates the winding of each connected part across shared edges
+// and counts the facets on the minority side, which are the ones wound the wrong way.
+// (In neighbours, Forward marks a neighbour that walks the shared edge in the same direction.)
+func countFlippedTriangles(neighbours [][]stlEdgeUse) int {
+	parity := make([]int, len(neighbours)) // 0 unvisited, 1 same as the seed, 2 flipped
+	flipped := 0
+	for seed := range neighbours {
+		if parity[seed] != 0 {
+			continue
+		}
+		parity[seed] = 1
+		counts := [3]int{0, 1, 0}
+		queue := []int{seed}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, neighbour := range neighbours[current] {
+				if parity[neighbour.Triangle] != 0 {
+					continue // Already oriented (conflicts in non-orientable meshes are ignored)
+				}
+				next := parity[current]
+				if neighbour.Forward {
+					next = 3 - next
+				}
+				parity[neighbour.Triangle] = next
+				counts[next]++
+				queue = append(queue, neighbour.Triangle)
+			}
+		}
+		flipped += min(counts[1], counts[2])
+	}
+	return flipped
+}
+
+// analyzeSTLFile parses and analyses an STL file on disk.
+func analyzeSTLFile(filePath string) (*stlAnalysis, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	mesh, err := parseSTL(data)
+	if err != nil {
+		return nil, err
+	}
+	return mesh.analyze(), nil
+}
+
+// subtract3 returns a - b.
+func subtract3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+// cross3 returns the cross product a × b.
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// dot3 returns the dot product a · b.
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// length3 returns the Euclidean length of a.
+func length3(a [3]float64) float64 {
+	return math.Sqrt(dot3(a, a))
+}
@@ -0,0 +1,135 @@
+package main // Define the main package
+
+import (
+	"encoding/binary" // Provides the little-endian binary STL layout
+	"math"            // Provides float bit conversion
+	"slices"          // Provides slice cloning
+	"strconv"         // Provides float formatting for ASCII STL
+	"strings"         // Provides string building for ASCII STL
+	"testing"         // Provides the test runner
+)
+
+// tetrahedron is a closed, consistently wound mesh with 10 mm edges along the axes.
+var tetrahedron = [][3][3]float32{
+	{{0, 0, 0}, {0, 10, 0}, {10, 0, 0}},
+	{{0, 0, 0}, {10, 0, 0}, {0, 0, 10}},
+	{{0, 0, 0}, {0, 0, 10}, {0, 10, 0}},
+	{{10, 0, 0}, {0, 10, 0}, {0, 0, 10}},
+}
+
+// binarySTL encodes the triangles as a binary STL whose header starts with "solid", as many exporters do.
+func binarySTL(triangles [][3][3]float32) []byte {
+	data := make([]byte, 84+50*len(triangles))
+	copy(data, "solid exported by a CAD tool")
+	binary.LittleEndian.PutUint32(data[80:], uint32(len(triangles)))
+	for index, triangle := range triangles {
+		offset := 84 + 50*index + 12 // The stored normal is left zero
+		for _, vertex := range triangle {
+			for axis, value := range vertex {
+				binary.LittleEndian.PutUint32(data[offset+4*axis:], math.Float32bits(value))
+			}
+			offset += 12
+		}
+	}
+	return data
+}
+
+// asciiSTL encodes the triangles as an ASCII STL.
+func asciiSTL(triangles [][3][3]float32) []byte {
+	var text strings.Builder
+	text.WriteString("solid test\n")
+	for _, triangle := range triangles {
+		text.WriteString("  facet normal 0 0 0\n    outer loop\n")
+		for _, vertex := range triangle {
+			text.WriteString("      vertex")
+			for _, value := range vertex {
+				text.WriteString(" " + strconv.FormatFloat(float64(value), 'e', 6, 32))
+			}
+			text.WriteString("\n")
+		}
+		text.WriteString("    endloop\n  endfacet\n")
+	}
+	text.WriteString("endsolid test\n")
+	return []byte(text.String())
+}
+
+// withVertex returns a copy of the tetrahedron with the first vertex of the first facet replaced.
+func withVertex(vertex [3]float32) [][3][3]float32 {
+	triangles := slices.Clone(tetrahedron)
+	triangles[0][0] = vertex
+	return triangles
+}
+
+// TestParseSTL checks format detection, facet parsing and the errors for broken files, which the
+// validator must reject too.
+func TestParseSTL(t *testing.T) {
+	nan, inf := float32(math.NaN()), float32(math.Inf(1))
+	tests := []struct {
+		name      string
+		data      []byte
+		format    string // Expected format, "" when parsing must fail
+		triangles int
+	}{
+		{"binary with solid header", binarySTL(tetrahedron), "binary", 4},
+		{"ascii", asciiSTL(tetrahedron), "ascii", 4},
+		{"ascii with surrounding whitespace", append([]byte("\n\n  "), asciiSTL(tetrahedron)...), "ascii", 4},
+		{"binary cut short", binarySTL(tetrahedron)[:84+50*3+20], "", 0},
+		{"ascii without facets", []byte("solid empty\nendsolid empty\n"), "", 0},
+		{"ascii facet with two vertices", []byte("solid s\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nendloop\nendfacet\nendsolid s\n"), "", 0},
+		{"ascii facet with a bad number", []byte("solid s\nfacet normal 0 0 1\nouter loop\nvertex 0 0 x\nvertex 1 0 0\nvertex 0 1 0\nendloop\nendfacet\nendsolid s\n"), "", 0},
+		{"binary with a NaN vertex", binarySTL(withVertex([3]float32{0, nan, 0})), "", 0},
+		{"binary with an infinite vertex", binarySTL(withVertex([3]float32{-inf, 0, 0})), "", 0},
+		{"ascii with a NaN vertex", asciiSTL(withVertex([3]float32{0, 0, nan})), "", 0},
+		{"ascii with an infinite vertex", asciiSTL(withVertex([3]float32{inf, 0, 0})), "", 0},
+		{"not an STL", []byte("PK\x03\x04 this is a zip file"), "", 0},
+		{"empty", nil, "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mesh, err := parseSTL(test.data)
+			if test.format == "" {
+				if err == nil {
+					t.Fatalf("parseSTL succeeded with %d triangles, want an error", len(mesh.Triangles))
+				}
+				if _, err := (stlValidator{}).validate(test.data); err == nil {
+					t.Errorf("stlValidator accepted the file")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSTL failed: %v", err)
+			}
+			if _, err := (stlValidator{}).validate(test.data); err != nil {
+				t.Errorf("stlValidator rejected the file: %v", err)
+			}
+			if mesh.Format != test.format || len(mesh.Triangles) != test.triangles {
+				t.Errorf("parseSTL = %s with %d triangles, want %s with %d", mesh.Format, len(mesh.Triangles), test.format, test.triangles)
+			}
+		})
+	}
+}
+
+// TestAnalyzeSTL checks the geometry summary of the closed tetrahedron.
+func TestAnalyzeSTL(t *testing.T) {
+	mesh, err := parseSTL(binarySTL(tetrahedron))
+	if err != nil {
+		t.Fatalf("parseSTL failed: %v", err)
+	}
+	analysis := mesh.analyze()
+	if analysis.Size != [3]float64{10, 10, 10} {
+		t.Errorf("size = %v, want [10 10 10]", analysis.Size)
+	}
+	if math.Abs(math.Abs(analysis.Volume)-1000.0/6) > 0.01 {
+		t.Errorf("volume = %v, want ±166.67", analysis.Volume)
+	}
+	if !analysis.Watertight || analysis.NonManifoldEdges != 0 || analysis.FlippedNormals != 0 {
+		t.Errorf("watertight = %v, non-manifold edges = %d, flipped = %d; want a closed mesh",
+			analysis.Watertight, analysis.NonManifoldEdges, analysis.FlippedNormals)
+	}
+
+	// Removing a facet opens the mesh
+	open, _ := parseSTL(binarySTL(tetrahedron[:3]))
+	if open.analyze().Watertight {
+		t.Errorf("mesh with a missing facet reported as watertight")
+	}
+}
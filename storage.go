@@ -0,0 +1,190 @@
+package main // Define the main package
+
+import (
+	"errors"        // Provides error values
+	"fmt"           // Provides formatted errors
+	"io"            // Provides basic interfaces to I/O primitives
+	"io/fs"         // Provides fs.ErrNotExist and directory walking
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path"          // Provides functions for manipulating slash-separated paths
+	"path/filepath" // Provides filepath manipulation functions
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides time-related functions
+)
+
+// storedFile describes one committed file in a storage backend.
+type storedFile struct {
+	Name     string    // Slash-separated path inside the storage (e.g. "PDFs/loris_manual.pdf")
+	Size     int64     // Size in bytes
+	Modified time.Time // Modification time as reported by the backend
+}
+
+// archiveStorage is a place archived files are written to: the working tree, a tarball or a bucket.
+// Files passed to put are staged and only become visible to stat, list and open once commit succeeds,
+// so an interrupted run never leaves partial files behind; abort discards everything staged.
+// stat and open return an error matching fs.ErrNotExist for files that are not there.
+type archiveStorage interface {
+	put(name string, contents io.Reader, size int64, modified time.Time) error
+	stat(name string) (storedFile, error)
+	list(prefix string) ([]storedFile, error)
+	open(name string) (io.ReadCloser, error)
+	commit() error
+	abort() error
+}
+
+// storageStagingPrefix starts the name of the directory local puts are staged in.
+const storageStagingPrefix = ".staging-"
+
+// validStorageName reports whether a name is a clean relative slash path that stays inside the storage.
+func validStorageName(name string) bool {
+	return name != "" && path.Clean(name) == name && !path.IsAbs(name) &&
+		name != ".." && !strings.HasPrefix(name, "../") && !strings.Contains(name, "\\")
+}
+
+// openStorage returns the backend for a destination: "s3://bucket/prefix" for an S3-compatible bucket,
+// a path ending in .tar or .tar.zst for a tarball, and any other path for a local directory.
+func openStorage(destination, endpoint, region string) (archiveStorage, error) {
+	switch {
+	case strings.HasPrefix(destination, "s3://"):
+		return newS3Storage(destination, endpoint, region)
+	case strings.HasSuffix(destination, ".tar") || strings.HasSuffix(destination, ".tar.zst"):
+		return newTarStorage(destination), nil
+	case destination == "":
+		return nil, errors.New("no destination given")
+	default:
+		return newLocalStorage(destination), nil
+	}
+}
+
+// localStorage keeps files in a directory. Puts are written into a staging directory next to them
+// and renamed into place on commit, which is atomic per file on a single filesystem.
+type localStorage struct {
+	root    string   // Directory the committed files live in
+	staging string   // Staging directory, created on the first put
+	staged  []string // Names put since the last commit or abort
+}
+
+// newLocalStorage returns the storage for a directory, which is created on the first commit if needed.
+func newLocalStorage(root string) *localStorage {
+	return &localStorage{root: root}
+}
+
+func (storage *localStorage) put(name string, contents io.Reader, size int64, modified time.Time) error {
+	if !validStorageName(name) {
+		return fmt.Errorf("invalid storage name %q", name)
+	}
+	if storage.staging == "" {
+		if err := os.MkdirAll(storage.root, 0o755); err != nil {
+			return err
+		}
+		staging, err := os.MkdirTemp(storage.root, storageStagingPrefix)
+		if err != nil {
+			return err
+		}
+		storage.staging = staging
+	}
+	stagedPath := filepath.Join(storage.staging, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(stagedPath), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(stagedPath)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(out, contents)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("wrote %d bytes of %s, expected %d", written, name, size)
+	}
+	if err != nil {
+		os.Remove(stagedPath)
+		return err
+	}
+	if !modified.IsZero() {
+		os.Chtimes(stagedPath, modified, modified)
+	}
+	storage.staged = append(storage.staged, name)
+	return nil
+}
+
+func (storage *localStorage) stat(name string) (storedFile, error) {
+	if !validStorageName(name) {
+		return storedFile{}, fmt.Errorf("invalid storage name %q", name)
+	}
+	info, err := os.Stat(filepath.Join(storage.root, filepath.FromSlash(name)))
+	if err != nil {
+		return storedFile{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return storedFile{}, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return storedFile{Name: name, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+func (storage *localStorage) list(prefix string) ([]storedFile, error) {
+	var files []storedFile
+	err := filepath.WalkDir(storage.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == storage.root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll // Nothing committed yet
+			}
+			return err
+		}
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), storageStagingPrefix) {
+			return fs.SkipDir
+		}
+		relative, err := filepath.Rel(storage.root, filePath)
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name := filepath.ToSlash(relative)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		// Links (e.g. into the object store) are listed with the size of their target
+		info, err := os.Stat(filePath)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, storedFile{Name: name, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+func (storage *localStorage) open(name string) (io.ReadCloser, error) {
+	if !validStorageName(name) {
+		return nil, fmt.Errorf("invalid storage name %q", name)
+	}
+	return os.Open(filepath.Join(storage.root, filepath.FromSlash(name)))
+}
+
+func (storage *localStorage) commit() error {
+	defer storage.abort()
+	for _, name := range storage.staged {
+		finalPath := filepath.Join(storage.root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(finalPath), 0o755); err != nil {
+			return err
+		}
+		// A link in the way (e.g. into the object store) is replaced, never written through
+		if info, err := os.Lstat(finalPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			os.Remove(finalPath)
+		}
+		if err := os.Rename(filepath.Join(storage.staging, filepath.FromSlash(name)), finalPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (storage *localStorage) abort() error {
+	storage.staged = nil
+	if storage.staging == "" {
+		return nil
+	}
+	err := os.RemoveAll(storage.staging)
+	storage.staging = ""
+	return err
+}
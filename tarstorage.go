@@ -0,0 +1,231 @@
+package main // Define the main package
+
+import (
+	"archive/tar"   // Provides the tar format
+	"bytes"         // Provides in-memory readers
+	"errors"        // Provides error values
+	"fmt"           // Provides formatted errors
+	"io"            // Provides basic interfaces to I/O primitives
+	"io/fs"         // Provides fs.ErrNotExist
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"strings"       // Provides string manipulation functions
+	"time"          // Provides time-related functions
+
+	"github.com/klauspost/compress/zstd" // Provides Zstandard compression for .tar.zst snapshots
+)
+
+// tarStorage writes a snapshot tarball, compressed with Zstandard when the name ends in .tar.zst.
+// Every put is streamed into a temporary file that replaces the snapshot on commit, so the snapshot
+// holds exactly the files put since the storage was opened. stat, list and open read the committed snapshot.
+type tarStorage struct {
+	path       string          // Snapshot file
+	temporary  *os.File        // Snapshot being written, created on the first put
+	compressor *zstd.Encoder   // Compression stream of .tar.zst snapshots
+	writer     *tar.Writer     // Tar stream into the temporary file or the compressor
+	staged     map[string]bool // Names put so far, since tar allows duplicates but a snapshot should not
+}
+
+// newTarStorage returns the storage for a tarball at snapshotPath.
+func newTarStorage(snapshotPath string) *tarStorage {
+	return &tarStorage{path: snapshotPath, staged: make(map[string]bool)}
+}
+
+// compressed reports whether the snapshot is a .tar.zst.
+func (storage *tarStorage) compressed() bool {
+	return strings.HasSuffix(storage.path, ".zst")
+}
+
+func (storage *tarStorage) put(name string, contents io.Reader, size int64, modified time.Time) error {
+	if !validStorageName(name) {
+		return fmt.Errorf("invalid storage name %q", name)
+	}
+	if size < 0 {
+		return fmt.Errorf("%s: tar entries need their size up front", name)
+	}
+	if storage.staged[name] {
+		return fmt.Errorf("%s was already put into %s", name, storage.path)
+	}
+	if storage.writer == nil {
+		if err := storage.begin(); err != nil {
+			return err
+		}
+	}
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modified.UTC().Truncate(time.Second),
+		Format:   tar.FormatPAX, // Long names and exact sizes without the old ustar limits
+	}
+	if err := storage.writer.WriteHeader(header); err != nil {
+		return err
+	}
+	// A short body would corrupt every entry after it, so the snapshot is unusable from here on
+	if written, err := io.Copy(storage.writer, contents); err != nil || written != size {
+		storage.abort()
+		if err == nil {
+			err = fmt.Errorf("wrote %d bytes of %s, expected %d", written, name, size)
+		}
+		return err
+	}
+	storage.staged[name] = true
+	return nil
+}
+
+// begin creates the temporary snapshot and the tar stream into it.
+func (storage *tarStorage) begin() error {
+	if err := os.MkdirAll(filepath.Dir(storage.path), 0o755); err != nil {
+		return err
+	}
+	temporary, err := os.Create(storage.path + extractTempSuffix)
+	if err != nil {
+		return err
+	}
+	storage.temporary = temporary
+	var stream io.Writer = temporary
+	if storage.compressed() {
+		compressor, err := zstd.NewWriter(temporary)
+		if err != nil {
+			storage.abort()
+			return err
+		}
+		storage.compressor = compressor
+		stream = compressor
+	}
+	storage.writer = tar.NewWriter(stream)
+	return nil
+}
+
+// readSnapshot calls visit for every regular file of the committed snapshot until it returns false.
+func (storage *tarStorage) readSnapshot(visit func(header *tar.Header, reader io.Reader) bool) error {
+	file, err := os.Open(storage.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var stream io.Reader = file
+	if storage.compressed() {
+		decompressor, err := zstd.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer decompressor.Close()
+		stream = decompressor
+	}
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && !visit(header, reader) {
+			return nil
+		}
+	}
+}
+
+func (storage *tarStorage) stat(name string) (storedFile, error) {
+	var found *storedFile
+	err := storage.readSnapshot(func(header *tar.Header, _ io.Reader) bool {
+		if header.Name == name {
+			found = &storedFile{Name: name, Size: header.Size, Modified: header.ModTime}
+		}
+		return found == nil
+	})
+	if err != nil {
+		return storedFile{}, err
+	}
+	if found == nil {
+		return storedFile{}, fmt.Errorf("%s in %s: %w", name, storage.path, fs.ErrNotExist)
+	}
+	return *found, nil
+}
+
+func (storage *tarStorage) list(prefix string) ([]storedFile, error) {
+	var files []storedFile
+	err := storage.readSnapshot(func(header *tar.Header, _ io.Reader) bool {
+		if strings.HasPrefix(header.Name, prefix) {
+			files = append(files, storedFile{Name: header.Name, Size: header.Size, Modified: header.ModTime})
+		}
+		return true
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil // No snapshot committed yet
+	}
+	return files, err
+}
+
+// open returns the contents of one file of the snapshot. Tarballs cannot seek to a member,
+// so the snapshot is scanned up to it and the member is read into memory.
+func (storage *tarStorage) open(name string) (io.ReadCloser, error) {
+	var contents []byte
+	var readErr error
+	found := false
+	err := storage.readSnapshot(func(header *tar.Header, reader io.Reader) bool {
+		if header.Name != name {
+			return true
+		}
+		found = true
+		contents, readErr = io.ReadAll(reader)
+		return false
+	})
+	if err == nil {
+		err = readErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s in %s: %w", name, storage.path, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(contents)), nil
+}
+
+func (storage *tarStorage) commit() error {
+	// Nothing was put: an empty snapshot is still a snapshot
+	if storage.writer == nil {
+		if err := storage.begin(); err != nil {
+			return err
+		}
+	}
+	err := storage.writer.Close()
+	if err == nil && storage.compressor != nil {
+		err = storage.compressor.Close()
+	}
+	if err == nil {
+		err = storage.temporary.Sync()
+	}
+	if closeErr := storage.temporary.Close(); err == nil {
+		err = closeErr
+	}
+	temporaryPath := storage.temporary.Name()
+	storage.writer, storage.compressor, storage.temporary = nil, nil, nil
+	storage.staged = make(map[string]bool)
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	return os.Rename(temporaryPath, storage.path)
+}
+
+func (storage *tarStorage) abort() error {
+	storage.staged = make(map[string]bool)
+	if storage.temporary == nil {
+		return nil
+	}
+	if storage.compressor != nil {
+		storage.compressor.Close()
+	}
+	storage.temporary.Close()
+	err := os.Remove(storage.temporary.Name())
+	storage.writer, storage.compressor, storage.temporary = nil, nil, nil
+	return err
+}
@@ -0,0 +1,355 @@
+package main // Define the main package
+
+import (
+	"archive/zip"     // Provides ZIP central directory parsing and CRC checks
+	"bytes"           // Provides bytes support
+	"encoding/binary" // Provides little-endian integer decoding
+	"errors"          // Provides error values
+	"fmt"             // Provides formatted errors and details
+	"hash/crc32"      // Provides the CRC-32 used by RAR headers
+	"image"           // Provides image.DecodeConfig
+	_ "image/jpeg"    // Registers the JPEG decoder
+	_ "image/png"     // Registers the PNG decoder
+	"io"              // Provides basic interfaces to I/O primitives
+	"log"             // Provides logging functions
+	"os"              // Provides functions to interact with the OS (files, etc.)
+	"time"            // Provides time-related functions
+)
+
+// assetValidator checks that the bytes of a file are a complete, readable file of its type.
+// On success it returns a short description of the contents (e.g. "13 pages" or "1920×1080 png").
+type assetValidator interface {
+	validate(data []byte) (string, error)
+}
+
+// validationResult is the outcome of the last format check of an archived file.
+type validationResult struct {
+	Valid     bool      `json:"valid"`
+	Detail    string    `json:"detail,omitempty"` // Description of the contents when valid
+	Error     string    `json:"error,omitempty"`  // Why the file was rejected
+	SHA256    string    `json:"sha256"`           // Contents the result applies to
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// validateAsset runs the validator of the asset type, if it has one.
+func validateAsset(asset *assetType, data []byte) (string, error) {
+	if asset == nil || asset.Validator == nil {
+		return "", nil
+	}
+	return asset.Validator.validate(data)
+}
+
+// validateArchiveFile validates a file already on disk and returns the result for the manifest.
+func validateArchiveFile(filePath string, asset *assetType, sum string) *validationResult {
+	result := &validationResult{SHA256: sum, CheckedAt: time.Now().UTC().Truncate(time.Second)}
+	data, err := os.ReadFile(filePath)
+	if err == nil {
+		result.Detail, err = validateAsset(asset, data)
+	}
+	if err != nil {
+		log.Printf("Validation failed for %s: %v", filePath, err)
+		result.Detail, result.Error = "", err.Error()
+		return result
+	}
+	result.Valid = true
+	return result
+}
+
+// pdfValidator checks the header, trailer, cross-references and page tree of a PDF.
+type pdfValidator struct{}
+
+func (pdfValidator) validate(data []byte) (string, error) {
+	pageCount, recovered, err := validatePDF(data)
+	if err != nil {
+		return "", err
+	}
+	if recovered {
+		return fmt.Sprintf("%d pages (cross-reference table rebuilt)", pageCount), nil
+	}
+	return fmt.Sprintf("%d pages", pageCount), nil
+}
+
+// imageValidator decodes the image header and checks the file is not cut off.
+type imageValidator struct {
+	Format string // Format image.DecodeConfig must report ("png" or "jpeg")
+}
+
+func (validator imageValidator) validate(data []byte) (string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("cannot decode image header: %v", err)
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return "", fmt.Errorf("image has no pixels (%d×%d)", config.Width, config.Height)
+	}
+	// DecodeConfig only reads the header, so check the end marker for truncation
+	switch format {
+	case "png":
+		// IEND is always followed by the same CRC, so a cut inside the last chunk is caught too
+		if !bytes.Contains(data[max(0, len(data)-64):], []byte("IEND\xae\x42\x60\x82")) {
+			return "", errors.New("missing PNG IEND chunk (file is probably truncated)")
+		}
+	case "jpeg":
+		if !bytes.Contains(data[max(0, len(data)-1024):], []byte{0xFF, 0xD9}) {
+			return "", errors.New("missing JPEG end-of-image marker (file is probably truncated)")
+		}
+	}
+	detail := fmt.Sprintf("%d×%d %s", config.Width, config.Height, format)
+	if format != validator.Format {
+		detail += fmt.Sprintf(" (stored as %s)", validator.Format)
+	}
+	return detail, nil
+}
+
+// zipValidator reads the central directory and decompresses every entry to check its CRC.
+type zipValidator struct{}
+
+func (zipValidator) validate(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("cannot read central directory: %v", err)
+	}
+	var total uint64
+	unchecked := 0
+	for _, file := range reader.File {
+		if file.Flags&0x1 != 0 {
+			unchecked++ // Encrypted entries cannot be checked without the password
+			continue
+		}
+		entry, err := file.Open()
+		if errors.Is(err, zip.ErrAlgorithm) {
+			unchecked++ // Compression methods Go cannot decompress
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", file.Name, err)
+		}
+		// Reading to the end verifies the CRC; never read past the declared size
+		read, err := io.Copy(io.Discard, io.LimitReader(entry, int64(file.UncompressedSize64)+1))
+		entry.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", file.Name, err)
+		}
+		if uint64(read) != file.UncompressedSize64 {
+			return "", fmt.Errorf("%s: expected %d bytes, got %d", file.Name, file.UncompressedSize64, read)
+		}
+		total += file.UncompressedSize64
+	}
+	detail := fmt.Sprintf("%d entries, %s uncompressed", len(reader.File), formatSize(int64(total)))
+	if unchecked > 0 {
+		detail += fmt.Sprintf(", %d not CRC-checked (encrypted or unsupported method)", unchecked)
+	}
+	return detail, nil
+}
+
+// RAR signatures of the two archive format versions.
+var (
+	rar4Signature = []byte("Rar!\x1a\x07\x00")
+	rar5Signature = []byte("Rar!\x1a\x07\x01\x00")
+)
+
+// rarValidator checks the signature and walks every block header, verifying header CRCs and sizes.
+type rarValidator struct{}
+
+func (rarValidator) validate(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, rar5Signature):
+		return walkRAR5(data)
+	case bytes.HasPrefix(data, rar4Signature):
+		return walkRAR4(data)
+	}
+	return "", errors.New("missing RAR signature")
+}
+
+// walkRAR4 walks the blocks of a RAR 1.5-4.x archive.
+func walkRAR4(data []byte) (string, error) {
+	files := 0
+	position := len(rar4Signature)
+	for position < len(data) {
+		if position+7 > len(data) {
+			return "", fmt.Errorf("truncated block header at offset %d", position)
+		}
+		headerCRC := binary.LittleEndian.Uint16(data[position:])
+		headerType := data[position+2]
+		flags := binary.LittleEndian.Uint16(data[position+3:])
+		headerSize := int(binary.LittleEndian.Uint16(data[position+5:]))
+		if headerSize < 7 || position+headerSize > len(data) {
+			return "", fmt.Errorf("block at offset %d has an invalid header size %d", position, headerSize)
+		}
+		header := data[position : position+headerSize]
+		if uint16(crc32.ChecksumIEEE(header[2:])) != headerCRC {
+			return "", fmt.Errorf("header CRC mismatch in block at offset %d", position)
+		}
+
+		// Data that follows the header: the packed size for file blocks, ADD_SIZE for other long blocks
+		var dataSize uint64
+		switch {
+		case (headerType == 0x74 || headerType == 0x7A) && headerSize >= 11:
+			dataSize = uint64(binary.LittleEndian.Uint32(header[7:]))
+			if flags&0x100 != 0 && headerSize >= 36 {
+				dataSize |= uint64(binary.LittleEndian.Uint32(header[32:])) << 32
+			}
+		case flags&0x8000 != 0 && headerSize >= 11:
+			dataSize = uint64(binary.LittleEndian.Uint32(header[7:]))
+		}
+		switch headerType {
+		case 0x73:
+			if flags&0x80 != 0 {
+				return fmt.Sprintf("RAR4, %d files, encrypted headers", files), nil
+			}
+		case 0x74:
+			files++
+		case 0x7B:
+			return fmt.Sprintf("RAR4, %d files", files), nil // End of archive; trailing data is ignored
+		}
+		next := uint64(position) + uint64(headerSize) + dataSize
+		if next > uint64(len(data)) {
+			return "", fmt.Errorf("block at offset %d extends past the end of the file (truncated)", position)
+		}
+		position = int(next)
+	}
+	// Old archives have no end block and simply stop after the last file
+	if files == 0 {
+		return "", errors.New("no file blocks and no end of archive block (file is probably truncated)")
+	}
+	return fmt.Sprintf("RAR4, %d files", files), nil
+}
+
+// readRARVarint reads a RAR5 variable-length integer and returns it with the number of bytes used.
+func readRARVarint(data []byte) (uint64, int, error) {
+	var value uint64
+	for index := 0; index < len(data) && index < 10; index++ {
+		value |= uint64(data[index]&0x7F) << (7 * index)
+		if data[index]&0x80 == 0 {
+			return value, index + 1, nil
+		}
+	}
+	return 0, 0, errors.New("truncated variable-length integer")
+}
+
+// walkRAR5 walks the blocks of a RAR 5.0 archive.
+func walkRAR5(data []byte) (string, error) {
+	files := 0
+	position := len(rar5Signature)
+	for position < len(data) {
+		if position+5 > len(data) {
+			return "", fmt.Errorf("truncated block header at offset %d", position)
+		}
+		headerCRC := binary.LittleEndian.Uint32(data[position:])
+		headerSize, sizeLength, err := readRARVarint(data[position+4:])
+		if err != nil {
+			return "", fmt.Errorf("block at offset %d: %v", position, err)
+		}
+		headerStart := position + 4 + sizeLength
+		headerEnd := uint64(headerStart) + headerSize
+		if headerSize == 0 || headerEnd > uint64(len(data)) {
+			return "", fmt.Errorf("block at offset %d has an invalid header size %d", position, headerSize)
+		}
+		// The CRC covers the size field and the header itself
+		if crc32.ChecksumIEEE(data[position+4:headerEnd]) != headerCRC {
+			return "", fmt.Errorf("header CRC mismatch in block at offset %d", position)
+		}
+		header := data[headerStart:headerEnd]
+		headerType, typeLength, err := readRARVarint(header)
+		if err != nil {
+			return "", fmt.Errorf("block at offset %d: %v", position, err)
+		}
+		flags, flagsLength, err := readRARVarint(header[typeLength:])
+		if err != nil {
+			return "", fmt.Errorf("block at offset %d: %v", position, err)
+		}
+		fields := header[typeLength+flagsLength:]
+		if flags&0x1 != 0 {
+			_, extraLength, err := readRARVarint(fields)
+			if err != nil {
+				return "", fmt.Errorf("block at offset %d: %v", position, err)
+			}
+			fields = fields[extraLength:]
+		}
+		var dataSize uint64
+		if flags&0x2 != 0 {
+			dataSize, _, err = readRARVarint(fields)
+			if err != nil {
+				return "", fmt.Errorf("block at offset %d: %v", position, err)
+			}
+		}
+		switch headerType {
+		case 2:
+			files++
+		case 4:
+			return fmt.Sprintf("RAR5, %d files, encrypted headers", files), nil
+		case 5:
+			return fmt.Sprintf("RAR5, %d files", files), nil
+		}
+		next := headerEnd + dataSize
+		if next > uint64(len(data)) {
+			return "", fmt.Errorf("block at offset %d extends past the end of the file (truncated)", position)
+		}
+		position = int(next)
+	}
+	return "", errors.New("missing end of archive block (file is probably truncated)")
+}
+
+// stlValidator checks that a binary STL matches its declared triangle count, or that an ASCII STL is complete,
+// and that every vertex is a finite coordinate.
+type stlValidator struct{}
+
+func (stlValidator) validate(data []byte) (string, error) {
+	// Binary: 80-byte header, triangle count, 50 bytes per triangle
+	if len(data) >= 84 {
+		triangles := uint64(binary.LittleEndian.Uint32(data[80:]))
+		if expected := 84 + 50*triangles; expected == uint64(len(data)) {
+			if triangles == 0 {
+				return "", errors.New("binary STL has no triangles")
+			}
+			if _, err := parseBinarySTL(data, int(triangles)); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("binary, %d triangles", triangles), nil
+		}
+	}
+	// ASCII: "solid" ... "endsolid", with every facet closed
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(bytes.ToLower(trimmed[:min(len(trimmed), 5)]), []byte("solid")) && bytes.Contains(trimmed, []byte("facet")) {
+		lower := bytes.ToLower(trimmed)
+		facets := bytes.Count(lower, []byte("endfacet"))
+		opened := bytes.Count(lower, []byte("facet normal"))
+		if !bytes.Contains(lower[max(0, len(lower)-1024):], []byte("endsolid")) {
+			return "", errors.New("ASCII STL has no endsolid (file is probably truncated)")
+		}
+		if facets == 0 || facets != opened {
+			return "", fmt.Errorf("ASCII STL has %d facets but %d endfacet markers", opened, facets)
+		}
+		if vertices := bytes.Count(lower, []byte("vertex")); vertices != 3*facets {
+			return "", fmt.Errorf("ASCII STL has %d vertices for %d facets", vertices, facets)
+		}
+		if _, err := parseASCIISTL(trimmed); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("ASCII, %d triangles", facets), nil
+	}
+	if len(data) < 84 {
+		return "", fmt.Errorf("file is too small for an STL (%d bytes)", len(data))
+	}
+	triangles := uint64(binary.LittleEndian.Uint32(data[80:]))
+	return "", fmt.Errorf("binary STL declares %d triangles (%d bytes) but the file has %d bytes", triangles, 84+50*triangles, len(data))
+}
+
+// stepValidator checks the ISO-10303-21 start and end markers and the HEADER and DATA sections.
+type stepValidator struct{}
+
+func (stepValidator) validate(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if !bytes.HasPrefix(trimmed, []byte("ISO-10303-21;")) {
+		return "", errors.New("missing ISO-10303-21 start marker")
+	}
+	if !bytes.HasSuffix(trimmed, []byte("END-ISO-10303-21;")) {
+		return "", errors.New("missing END-ISO-10303-21 end marker (file is probably truncated)")
+	}
+	for _, section := range []string{"HEADER;", "DATA;", "ENDSEC;"} {
+		if !bytes.Contains(trimmed, []byte(section)) {
+			return "", fmt.Errorf("missing %s section marker", section)
+		}
+	}
+	return "ISO 10303-21 exchange file", nil
+}
@@ -0,0 +1,195 @@
+package main // Define the main package
+
+import (
+	"crypto/sha256" // Provides hashing of files read for validation
+	"encoding/hex"  // Provides hex encoding of hashes
+	"flag"          // Provides command line flag parsing
+	"fmt"           // Provides formatted output
+	"maps"          // Provides map iteration helpers
+	"os"            // Provides functions to interact with the OS (files, etc.)
+	"path/filepath" // Provides filepath manipulation functions
+	"runtime"       // Provides the number of CPUs for the worker count
+	"slices"        // Provides slice sorting helpers
+	"strings"       // Provides string comparison
+	"sync"          // Provides the worker wait group
+	"sync/atomic"   // Provides the progress counters
+	"time"          // Provides time-related functions
+)
+
+// Outcomes of verifying one file against the manifest.
+const (
+	verifyOK        = "OK"
+	verifyModified  = "MODIFIED"  // Contents differ from the recorded SHA-256
+	verifyMissing   = "MISSING"   // In the manifest but not on disk
+	verifyUntracked = "UNTRACKED" // On disk in a type folder but not in the manifest
+	verifyInvalid   = "INVALID"   // Hash matches but the format check now fails (with -validate)
+)
+
+// fileVerification is the outcome for one file.
+type fileVerification struct {
+	Path   string
+	Status string
+	Detail string
+}
+
+// runVerify implements the verify command: rehash every archived file in parallel, compare it with the
+// manifest and report modified, missing and untracked files. It exits with 1 if anything does not match.
+func runVerify(args []string) int {
+	// Parse the command line options.
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	validate := flags.Bool("validate", false, "also run the format validators again on every file")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of files hashed in parallel")
+	verbose := flags.Bool("v", false, "also list the files that verified correctly")
+	flags.Parse(args)
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	archive := loadManifest(manifestPath)
+	var results []fileVerification
+	var queue []*manifestEntry
+	var totalBytes int64
+	for _, key := range slices.Sorted(maps.Keys(archive.Entries)) {
+		entry := archive.Entries[key]
+		if entry.AliasOf != "" {
+			continue // Removed on purpose in favour of a near-duplicate
+		}
+		if !fileExists(filepath.FromSlash(key)) {
+			results = append(results, fileVerification{Path: key, Status: verifyMissing})
+			continue
+		}
+		queue = append(queue, entry)
+		totalBytes += entry.Size
+	}
+	for _, onDisk := range archiveFilesOnDisk() {
+		if archive.Entries[onDisk] == nil {
+			results = append(results, fileVerification{Path: onDisk, Status: verifyUntracked})
+		}
+	}
+
+	// Hash in parallel, reporting progress on stderr while the workers run
+	var doneFiles, doneBytes atomic.Int64
+	verified := make([]fileVerification, len(queue))
+	jobs := make(chan int)
+	var workerGroup sync.WaitGroup
+	for range *workers {
+		workerGroup.Add(1)
+		go func() {
+			defer workerGroup.Done()
+			for index := range jobs {
+				verified[index] = verifyEntry(queue[index], *validate)
+				doneFiles.Add(1)
+				doneBytes.Add(queue[index].Size)
+			}
+		}()
+	}
+	stopProgress := showVerifyProgress(&doneFiles, &doneBytes, int64(len(queue)), totalBytes)
+	for index := range queue {
+		jobs <- index
+	}
+	close(jobs)
+	workerGroup.Wait()
+	stopProgress()
+	results = append(results, verified...)
+
+	// Report sorted by path, problems always and matches only with -v
+	slices.SortFunc(results, func(left, right fileVerification) int { return strings.Compare(left.Path, right.Path) })
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+		if result.Status == verifyOK && !*verbose {
+			continue
+		}
+		line := fmt.Sprintf("%-9s %s", result.Status, result.Path)
+		if result.Detail != "" {
+			line += " (" + result.Detail + ")"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d ok, %d modified, %d missing, %d untracked", counts[verifyOK], counts[verifyModified], counts[verifyMissing], counts[verifyUntracked])
+	if *validate {
+		fmt.Printf(", %d invalid", counts[verifyInvalid])
+	}
+	fmt.Println()
+	if counts[verifyOK] != len(results) {
+		return 1
+	}
+	return 0
+}
+
+// verifyEntry rehashes one archived file and, if asked, runs its format validator again.
+func verifyEntry(entry *manifestEntry, validate bool) fileVerification {
+	result := fileVerification{Path: entry.Path, Status: verifyOK}
+	filePath := filepath.FromSlash(entry.Path)
+	var size int64
+	var sum string
+	var data []byte
+	var err error
+	if validate {
+		// The validators need the whole file, so hash the same bytes instead of reading twice
+		data, err = os.ReadFile(filePath)
+		digest := sha256.Sum256(data)
+		size, sum = int64(len(data)), hex.EncodeToString(digest[:])
+	} else {
+		size, sum, err = hashFile(filePath)
+	}
+	switch {
+	case os.IsNotExist(err):
+		result.Status = verifyMissing
+		return result
+	case err != nil:
+		result.Status, result.Detail = verifyMissing, err.Error()
+		return result
+	case sum != entry.SHA256:
+		result.Status = verifyModified
+		if size != entry.Size {
+			result.Detail = fmt.Sprintf("%d bytes, manifest has %d", size, entry.Size)
+		} else {
+			result.Detail = "same size, different contents"
+		}
+		return result
+	}
+	if validate {
+		if _, err := validateAsset(assetTypeByExtension(filepath.Ext(entry.Path)), data); err != nil {
+			result.Status, result.Detail = verifyInvalid, err.Error()
+		}
+	}
+	return result
+}
+
+// showVerifyProgress redraws a progress line on stderr until the returned function is called.
+// When stderr is not a terminal only the final line is printed.
+func showVerifyProgress(doneFiles, doneBytes *atomic.Int64, totalFiles, totalBytes int64) func() {
+	info, err := os.Stderr.Stat()
+	interactive := err == nil && info.Mode()&os.ModeCharDevice != 0
+	draw := func(end string) {
+		percent := 100.0
+		if totalBytes > 0 {
+			percent = float64(doneBytes.Load()) * 100 / float64(totalBytes)
+		}
+		fmt.Fprintf(os.Stderr, "\rVerified %d/%d files, %s of %s (%.0f%%)%s",
+			doneFiles.Load(), totalFiles, formatSize(doneBytes.Load()), formatSize(totalBytes), percent, end)
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if interactive {
+					draw("")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		draw("\n")
+	}
+}
@@ -0,0 +1,24 @@
+package main // Define the main package
+
+// walksnailSite is the built-in adapter for the Walksnail website. Its archive lives in sites/walksnail/,
+// apart from the Caddx files that also include many Walksnail manuals.
+var walksnailSite = &shopifySite{
+	Name:  "walksnail",
+	Title: "Walksnail",
+	Seeds: []string{
+		"https://www.walksnail.app/pages/download",
+	},
+	Hosts:    []string{"walksnail.app"},
+	Products: walksnailProductRules,
+}
+
+// walksnailProductRules are tried in order, so more specific products come before their families.
+var walksnailProductRules = []productRule{
+	{Name: "Walksnail Avatar Moonlight", Patterns: [][]string{{"avatar", "moonlight"}, {"moonlight"}}},
+	{Name: "Walksnail Avatar Mini 1S", Patterns: [][]string{{"avatar", "mini", "1s"}, {"mini", "1s"}}},
+	{Name: "Walksnail Avatar V2", Patterns: [][]string{{"avatar", "v2"}}},
+	{Name: "Walksnail Avatar HD Goggles X", Patterns: [][]string{{"goggles", "x"}, {"goggle", "x"}, {"googles", "x"}, {"gogglesx"}}},
+	{Name: "Walksnail Avatar HD Goggles L", Patterns: [][]string{{"goggles", "l"}}},
+	{Name: "Walksnail Avatar VRX", Patterns: [][]string{{"vrx"}}},
+	{Name: "Walksnail Avatar", Patterns: [][]string{{"avatar"}, {"walksnail"}}},
+}